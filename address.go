@@ -0,0 +1,210 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Address は日本の住所を構成要素に分けたもの。番地などの数字は半角算用数字にそろえる。
+type Address struct {
+	Prefecture string `json:"prefecture"`
+	City       string `json:"city"`
+	Ward       string `json:"ward,omitempty"`
+	Town       string `json:"town"`
+	Chome      string `json:"chome,omitempty"`
+	Banchi     string `json:"banchi,omitempty"`
+	Go         string `json:"go,omitempty"`
+	Building   string `json:"building,omitempty"`
+}
+
+func (a *Address) String() string {
+	var b strings.Builder
+	b.WriteString(a.Prefecture + a.City + a.Ward + a.Town)
+	nums := []string{}
+	for _, n := range []string{a.Chome, a.Banchi, a.Go} {
+		if n != "" {
+			nums = append(nums, n)
+		}
+	}
+	b.WriteString(strings.Join(nums, "-"))
+	if a.Building != "" {
+		b.WriteString(" " + a.Building)
+	}
+	return b.String()
+}
+
+var (
+	prefecturePattern = regexp.MustCompile(`^(東京都|北海道|(?:京都|大阪)府|.{2,3}県)`)
+	cityPatterns      = []*regexp.Regexp{
+		regexp.MustCompile(`^.+?郡.+?[町村]`),
+		regexp.MustCompile(`^.+?市`),
+		regexp.MustCompile(`^.+?区`),
+		regexp.MustCompile(`^.+?[町村]`),
+	}
+	// 政令指定都市は「市」の後に行政区が続く
+	wardPattern = regexp.MustCompile(`^.+?区`)
+	// 「一丁目12番9号」「1丁目12-9」「1-12-9」のような丁目・番地・号
+	blockPattern = regexp.MustCompile(
+		`^(?:(\d+)丁目\s*)?(\d+)(?:番地?|-)?(?:(\d+)(?:号|-)?)?(?:(\d+)(?:号)?)?`)
+	// ダッシュは normalizeDashes の前の異体字（長音記号「ー」を含む）も受け付ける
+	blockPrefixPattern = regexp.MustCompile(
+		`^(?:(?:\d+|[〇一二三四五六七八九十百千]+)(?:丁目|番地?|[\-\x{2010}-\x{2015}\x{2212}\x{30fc}])\s*)*(?:(?:\d+|[〇一二三四五六七八九十百千]+)号?)?`)
+	kanjiBlockPattern  = regexp.MustCompile(`[〇一二三四五六七八九十百千]+(?:丁目|番地|番[\d〇一二三四五六七八九十])`)
+	kanjiNumberPattern = regexp.MustCompile(`[〇一二三四五六七八九十百千]+`)
+	kanjiDigits        = map[rune]int{
+		'〇': 0, '一': 1, '二': 2, '三': 3, '四': 4, '五': 5, '六': 6, '七': 7, '八': 8, '九': 9,
+	}
+)
+
+// ParseAddress は「東京都新宿区西新宿1-12-9」「神奈川県横浜市西区南幸一丁目5番1号 ○○ビル3F」
+// のような住所を都道府県・市区町村・町域・丁目・番地・号・建物名に分ける。
+// 解析できなかった部分は Town に残す。
+func ParseAddress(location string) *Address {
+	// 長音記号「ー」をハイフンにしないよう、normalizeDashes は丁目・番地・号の部分だけにかける
+	s := strings.TrimSpace(normalizeWidth(location))
+	s = strings.TrimPrefix(s, "〒")
+	if i := strings.IndexAny(s, "0123456789"); i == 0 {
+		// 郵便番号を読み飛ばす
+		if j := strings.IndexFunc(s, func(r rune) bool { return r == ' ' }); j > 0 {
+			s = strings.TrimSpace(s[j:])
+		}
+	}
+
+	a := &Address{}
+	if m := prefecturePattern.FindString(s); m != "" {
+		a.Prefecture = m
+		s = s[len(m):]
+	}
+	if m := matchCity(s); m != "" {
+		a.City = m
+		s = s[len(m):]
+		if strings.HasSuffix(a.City, "市") {
+			if w := wardPattern.FindString(s); w != "" && !strings.ContainsAny(w, "0123456789") {
+				a.Ward = w
+				s = s[len(w):]
+			}
+		}
+	}
+
+	// 町域は最初の数字（算用数字、または漢数字の丁目・番地）までとする
+	townEnd := len(s)
+	if i := strings.IndexAny(s, "0123456789"); i >= 0 {
+		townEnd = i
+	}
+	if loc := kanjiBlockPattern.FindStringIndex(s); loc != nil && loc[0] < townEnd {
+		townEnd = loc[0]
+	}
+	a.Town = strings.TrimSpace(s[:townEnd])
+	s = s[townEnd:]
+
+	// 建物名の漢数字や長音記号まで置き換えないよう、丁目・番地・号の部分だけを算用数字と '-' にする
+	prefix := blockPrefixPattern.FindString(s)
+	block := kanjiToArabic(normalizeDashes(prefix))
+	if m := blockPattern.FindStringSubmatch(block); m != nil && m[0] != "" {
+		nums := []string{}
+		for _, n := range m[2:] {
+			if n != "" {
+				nums = append(nums, n)
+			}
+		}
+		if m[1] != "" {
+			a.Chome = m[1]
+		} else if len(nums) == 3 {
+			a.Chome, nums = nums[0], nums[1:]
+		}
+		if len(nums) > 0 {
+			a.Banchi = nums[0]
+		}
+		if len(nums) > 1 {
+			a.Go = nums[1]
+		}
+		block = block[len(m[0]):]
+	}
+	a.Building = strings.TrimSpace(strings.TrimLeft(block+s[len(prefix):], "- "))
+	return a
+}
+
+// matchCity は先頭の市区町村名を返す。「新宿区市谷」の「市」のような町域内の文字に
+// 引きずられないよう、候補のうち最も短いものを選ぶ。
+func matchCity(s string) string {
+	city := ""
+	for _, p := range cityPatterns {
+		if m := p.FindString(s); m != "" && (city == "" || len(m) < len(city)) {
+			city = m
+		}
+	}
+	// 四日市市・廿日市市のように市名自体に「市」を含む場合
+	if strings.HasSuffix(city, "市") && strings.HasPrefix(s[len(city):], "市") {
+		city += "市"
+	}
+	return city
+}
+
+// kanjiToArabic は「十二」「二十」「一〇五」のような漢数字を算用数字に置き換える。
+func kanjiToArabic(s string) string {
+	return kanjiNumberPattern.ReplaceAllStringFunc(s, func(k string) string {
+		total, current := 0, 0
+		positional := true
+		for _, r := range k {
+			switch r {
+			case '十', '百', '千':
+				positional = false
+			}
+		}
+		if positional {
+			var b strings.Builder
+			for _, r := range k {
+				b.WriteString(strconv.Itoa(kanjiDigits[r]))
+			}
+			return b.String()
+		}
+		for _, r := range k {
+			unit := 0
+			switch r {
+			case '十':
+				unit = 10
+			case '百':
+				unit = 100
+			case '千':
+				unit = 1000
+			default:
+				current = kanjiDigits[r]
+				continue
+			}
+			if current == 0 {
+				current = 1
+			}
+			total += current * unit
+			current = 0
+		}
+		return strconv.Itoa(total + current)
+	})
+}
+
+func filterByWard(cafes []NetCafe, ward string) []NetCafe {
+	var results []NetCafe
+	for _, cafe := range cafes {
+		if cafe.Address.MatchesWard(ward) {
+			results = append(results, cafe)
+		}
+	}
+	return results
+}
+
+// MatchesWard は住所の市区町村または行政区が ward と一致するかどうかを返す。
+// 「新宿」のように「区」「市」を省いた指定も受け付ける。
+func (a *Address) MatchesWard(ward string) bool {
+	if a == nil || ward == "" {
+		return false
+	}
+	for _, name := range []string{a.City, a.Ward} {
+		if name == "" {
+			continue
+		}
+		if name == ward || strings.TrimRight(name, "区市町村") == ward {
+			return true
+		}
+	}
+	return false
+}
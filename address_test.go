@@ -0,0 +1,122 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAddress(t *testing.T) {
+	tests := []struct {
+		location string
+		expected Address
+	}{
+		{
+			"東京都新宿区西新宿1-12-9",
+			Address{Prefecture: "東京都", City: "新宿区", Town: "西新宿", Chome: "1", Banchi: "12", Go: "9"},
+		},
+		{
+			"東京都新宿区市谷田町２－７－１５",
+			Address{Prefecture: "東京都", City: "新宿区", Town: "市谷田町", Chome: "2", Banchi: "7", Go: "15"},
+		},
+		{
+			"神奈川県横浜市西区南幸一丁目5番1号 三井ビル3F",
+			Address{Prefecture: "神奈川県", City: "横浜市", Ward: "西区", Town: "南幸", Chome: "1", Banchi: "5", Go: "1", Building: "三井ビル3F"},
+		},
+		{
+			"大阪府大阪市北区角田町十二番地",
+			Address{Prefecture: "大阪府", City: "大阪市", Ward: "北区", Town: "角田町", Banchi: "12"},
+		},
+		{
+			"三重県四日市市諏訪栄町7-34",
+			Address{Prefecture: "三重県", City: "四日市市", Town: "諏訪栄町", Banchi: "7", Go: "34"},
+		},
+		{
+			"東京都西多摩郡瑞穂町箱根ケ崎2335",
+			Address{Prefecture: "東京都", City: "西多摩郡瑞穂町", Town: "箱根ケ崎", Banchi: "2335"},
+		},
+		{
+			"〒160-0023 東京都新宿区西新宿１丁目１２−９ 第二ビル",
+			Address{Prefecture: "東京都", City: "新宿区", Town: "西新宿", Chome: "1", Banchi: "12", Go: "9", Building: "第二ビル"},
+		},
+		{
+			"東京都千代田区三番町5-1",
+			Address{Prefecture: "東京都", City: "千代田区", Town: "三番町", Banchi: "5", Go: "1"},
+		},
+		{
+			"東京都新宿区西新宿1-12-9 センタービル",
+			Address{Prefecture: "東京都", City: "新宿区", Town: "西新宿", Chome: "1", Banchi: "12", Go: "9", Building: "センタービル"},
+		},
+		{
+			// 番地の区切りに使われた長音記号はハイフンとして扱い、建物名の長音記号はそのまま残す
+			"東京都豊島区東池袋1ー2ー3 ハーモニータワー2F",
+			Address{Prefecture: "東京都", City: "豊島区", Town: "東池袋", Chome: "1", Banchi: "2", Go: "3", Building: "ハーモニータワー2F"},
+		},
+		{
+			"渋谷区道玄坂2-29-5 ザ・プライム6F",
+			Address{City: "渋谷区", Town: "道玄坂", Chome: "2", Banchi: "29", Go: "5", Building: "ザ・プライム6F"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.location, func(t *testing.T) {
+			got := ParseAddress(tt.location)
+			if !reflect.DeepEqual(*got, tt.expected) {
+				t.Errorf("unexpected address:\ngot: %+v\nexpected: %+v", *got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestKanjiToArabic(t *testing.T) {
+	tests := map[string]string{
+		"一":   "1",
+		"十":   "10",
+		"十二":  "12",
+		"二十":  "20",
+		"百五":  "105",
+		"一〇五": "105",
+	}
+	for input, expected := range tests {
+		if got := kanjiToArabic(input); got != expected {
+			t.Errorf("kanjiToArabic(%q): expected %q, got %q", input, expected, got)
+		}
+	}
+}
+
+func TestAddress_MatchesWard(t *testing.T) {
+	shinjuku := ParseAddress("東京都新宿区西新宿1-12-9")
+	yokohama := ParseAddress("神奈川県横浜市西区南幸1-5-1")
+
+	tests := []struct {
+		address  *Address
+		ward     string
+		expected bool
+	}{
+		{shinjuku, "新宿区", true},
+		{shinjuku, "新宿", true},
+		{shinjuku, "西新宿", false},
+		{yokohama, "西区", true},
+		{yokohama, "横浜市", true},
+		{nil, "新宿区", false},
+	}
+	for _, tt := range tests {
+		if got := tt.address.MatchesWard(tt.ward); got != tt.expected {
+			t.Errorf("MatchesWard(%q) on %v: expected %v, got %v", tt.ward, tt.address, tt.expected, got)
+		}
+	}
+}
+
+func TestFilterByWard(t *testing.T) {
+	service := NewNetCafeService()
+
+	// 「新宿」の部分一致では歌舞伎町・西新宿の両方に加え町名も拾うが、区指定なら新宿区のみ
+	results := filterByWard(service.GetAll(), "新宿区")
+	if len(results) != 2 {
+		t.Errorf("expected 2 stores in 新宿区, got %d", len(results))
+	}
+
+	results = filterByWard(service.GetAll(), "千代田")
+	if len(results) != 1 || results[0].Name != "秋葉原店" {
+		t.Errorf("unexpected results for 千代田: %+v", results)
+	}
+}
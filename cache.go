@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	defaultCacheTTL = 6 * time.Hour
+	cacheVersion    = 1
+)
+
+// SnapshotCache は取得元ごとの ScrapeAll の結果をファイルに保存し、TTL 内なら再利用する。
+type SnapshotCache struct {
+	path      string
+	ttl       time.Duration
+	sourceTTL map[string]time.Duration
+	now       func() time.Time
+}
+
+type cacheFile struct {
+	Version int                     `json:"version"`
+	Sources map[string]cachedSource `json:"sources"`
+}
+
+type cachedSource struct {
+	// BaseURL が現在の接続先と異なるエントリは使わない（-base-url で差し替えた場合など）。
+	BaseURL   string    `json:"base_url"`
+	FetchedAt time.Time `json:"fetched_at"`
+	// Prefectures は取得した都道府県のコード（例: 13,27）。対象が異なるエントリは使わない。
+	Prefectures string `json:"prefectures"`
+	// Details は詳細ページまで取得した結果かどうか。詳細を求められたときは true のものだけ使う。
+	Details bool      `json:"details"`
+	Stores  []NetCafe `json:"stores"`
+}
+
+// NewSnapshotCache は path に保存するキャッシュを返す。sourceTTL で取得元ごとに ttl を上書きできる。
+func NewSnapshotCache(path string, ttl time.Duration, sourceTTL map[string]time.Duration) *SnapshotCache {
+	return &SnapshotCache{
+		path:      path,
+		ttl:       ttl,
+		sourceTTL: sourceTTL,
+		now:       time.Now,
+	}
+}
+
+// defaultCacheDir は XDG のキャッシュディレクトリ（$XDG_CACHE_HOME、既定は ~/.cache）配下の
+// netcafe-go 用ディレクトリを返す。
+func defaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "netcafe-go"), nil
+}
+
+// TTL は取得元 chainID のキャッシュの有効期間を返す。
+func (c *SnapshotCache) TTL(chainID string) time.Duration {
+	if ttl, ok := c.sourceTTL[chainID]; ok {
+		return ttl
+	}
+	return c.ttl
+}
+
+func (c *SnapshotCache) load() (map[string]cachedSource, error) {
+	b, err := os.ReadFile(c.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return map[string]cachedSource{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var f cacheFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("invalid cache file %s: %w", c.path, err)
+	}
+	if f.Version != cacheVersion || f.Sources == nil {
+		return map[string]cachedSource{}, nil
+	}
+	return f.Sources, nil
+}
+
+func (c *SnapshotCache) save(sources map[string]cachedSource) error {
+	b, err := json.Marshal(cacheFile{Version: cacheVersion, Sources: sources})
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(c.path, b)
+}
+
+// writeFileAtomic は一時ファイルに書いてから置き換え、書き込み途中のファイルを読まないようにする。
+func writeFileAtomic(path string, b []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// ScrapeAll はキャッシュが TTL 内の取得元はキャッシュを使い、それ以外だけを scraper で取得する。
+// refresh が true ならキャッシュの鮮度に関係なくすべて取得し直す（HTTP のレスポンスのキャッシュも再検証する）。取得に失敗した取得元は
+// 期限切れでもキャッシュがあればそれで代替する（SourceResult の Err は残す）。
+// 取得できた結果はキャッシュに保存する。
+func (c *SnapshotCache) ScrapeAll(ctx context.Context, scraper *Scraper, refresh bool) (*ScrapeResult, error) {
+	entries, err := c.load()
+	if err != nil {
+		log.Printf("ignoring snapshot cache: %v", err)
+		entries = map[string]cachedSource{}
+	}
+	now := c.now()
+
+	results := make([]SourceResult, len(scraper.sources))
+	var stale []Source
+	for i, src := range scraper.sources {
+		entry, ok := entries[src.ChainID()]
+		usable := ok && c.matches(entry, scraper, src) && (entry.Details || scraper.detailConcurrency == 0)
+		if usable && !refresh && now.Sub(entry.FetchedAt) < c.TTL(src.ChainID()) {
+			results[i] = SourceResult{
+				Source:    src.Name(),
+				ChainID:   src.ChainID(),
+				Stores:    withSource(entry.Stores, src),
+				Count:     len(entry.Stores),
+				FetchedAt: entry.FetchedAt,
+				Cached:    true,
+			}
+			continue
+		}
+		stale = append(stale, src)
+	}
+
+	if len(stale) > 0 {
+		sub := *scraper
+		sub.sources = stale
+		scrapeCtx := ctx
+		if refresh {
+			// HTTP キャッシュの期間内のページも古いかもしれないので再検証させる
+			scrapeCtx = withRevalidate(ctx)
+		}
+		scraped, _ := sub.ScrapeAll(scrapeCtx)
+
+		updated := false
+		j := 0
+		for i := range results {
+			if results[i].Cached {
+				continue
+			}
+			r := scraped.Sources[j]
+			src := stale[j]
+			j++
+
+			if r.OK() {
+				r.FetchedAt = now
+				entries[r.ChainID] = cachedSource{
+					BaseURL:     scraper.baseURL(src),
+					FetchedAt:   now,
+					Prefectures: prefectureKey(scraper.prefectures),
+					// 詳細ページが一部欠けた結果は、次の実行で詳細を取り直す
+					Details: scraper.detailConcurrency > 0 && r.DetailErr == nil,
+					Stores:  r.Stores,
+				}
+				updated = true
+			} else if entry, ok := entries[r.ChainID]; ok && c.matches(entry, scraper, src) {
+				r.Stores = withSource(entry.Stores, src)
+				r.Count = len(entry.Stores)
+				r.FetchedAt = entry.FetchedAt
+				r.Cached = true
+			}
+			results[i] = r
+		}
+
+		if updated {
+			if err := c.save(entries); err != nil {
+				log.Printf("failed to save snapshot cache: %v", err)
+			}
+		}
+	}
+
+	result := &ScrapeResult{Sources: results}
+	for _, r := range results {
+		result.Stores = append(result.Stores, r.Stores...)
+	}
+	result.Stores = mergeStores(result.Stores, scraper.sourceOrder())
+	return result, scrapeError(results)
+}
+
+// matches は entry が scraper の現在の接続先・都道府県で src から取得したものかどうかを返す。
+func (c *SnapshotCache) matches(entry cachedSource, scraper *Scraper, src Source) bool {
+	return entry.BaseURL == scraper.baseURL(src) && entry.Prefectures == prefectureKey(scraper.prefectures)
+}
+
+// cacheTTLFlag は -cache-ttl 6h（全取得元の既定）と -cache-ttl chain=1h（取得元ごと）を受け付ける。
+type cacheTTLFlag struct {
+	ttl       time.Duration
+	sourceTTL map[string]time.Duration
+}
+
+func (f *cacheTTLFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.ttl.String()
+}
+
+func (f *cacheTTLFlag) Set(value string) error {
+	chainID, ttlText, ok := strings.Cut(value, "=")
+	if !ok {
+		chainID, ttlText = "", value
+	}
+	ttl, err := time.ParseDuration(ttlText)
+	if err != nil || ttl < 0 || (ok && chainID == "") {
+		return fmt.Errorf("expected duration or chain=duration, got %q", value)
+	}
+
+	if chainID == "" {
+		f.ttl = ttl
+		return nil
+	}
+	if f.sourceTTL == nil {
+		f.sourceTTL = make(map[string]time.Duration)
+	}
+	f.sourceTTL[chainID] = ttl
+	return nil
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingSource は Scrape の呼び出し回数を数える取得元を返す。
+func countingSource(chainID string, calls *atomic.Int32, cafes []NetCafe, err *error) fakeSource {
+	return fakeSource{name: chainID, chainID: chainID, scrape: func(ctx context.Context) ([]NetCafe, error) {
+		calls.Add(1)
+		if *err != nil {
+			return nil, *err
+		}
+		return cafes, nil
+	}}
+}
+
+func newTestCache(t *testing.T, ttl time.Duration) (*SnapshotCache, *time.Time) {
+	t.Helper()
+	clock := time.Date(2026, 10, 20, 12, 0, 0, 0, jst)
+	cache := NewSnapshotCache(filepath.Join(t.TempDir(), "netcafe-go", "stores.json"), ttl, nil)
+	cache.now = func() time.Time { return clock }
+	return cache, &clock
+}
+
+func TestSnapshotCache_ScrapeAll(t *testing.T) {
+	var calls atomic.Int32
+	var scrapeErr error
+	scraper := NewScraper()
+	scraper.sources = []Source{countingSource("a", &calls, []NetCafe{{Name: "A店"}}, &scrapeErr)}
+
+	cache, clock := newTestCache(t, time.Hour)
+
+	// 初回はキャッシュがないので取得する
+	result, err := cache.ScrapeAll(context.Background(), scraper, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls.Load() != 1 || result.Sources[0].Cached {
+		t.Fatalf("expected a fresh scrape, calls=%d cached=%v", calls.Load(), result.Sources[0].Cached)
+	}
+	if _, err := os.Stat(cache.path); err != nil {
+		t.Fatalf("expected cache file to be written: %v", err)
+	}
+
+	// TTL 内はキャッシュを使う
+	*clock = clock.Add(30 * time.Minute)
+	result, err = cache.ScrapeAll(context.Background(), scraper, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls.Load() != 1 || !result.Sources[0].Cached {
+		t.Errorf("expected cached result, calls=%d", calls.Load())
+	}
+	if len(result.Stores) != 1 || result.Stores[0].Name != "A店" {
+		t.Errorf("unexpected cached stores: %+v", result.Stores)
+	}
+
+	// refresh なら TTL 内でも取得し直す
+	if _, err := cache.ScrapeAll(context.Background(), scraper, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("expected refresh to scrape, calls=%d", calls.Load())
+	}
+
+	// TTL 切れは取得し直す
+	*clock = clock.Add(2 * time.Hour)
+	if _, err := cache.ScrapeAll(context.Background(), scraper, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("expected expired cache to scrape, calls=%d", calls.Load())
+	}
+}
+
+func TestSnapshotCache_RefreshRevalidates(t *testing.T) {
+	var revalidated []bool
+	scraper := NewScraper()
+	scraper.sources = []Source{fakeSource{chainID: "a", scrape: func(ctx context.Context) ([]NetCafe, error) {
+		revalidated = append(revalidated, revalidateFrom(ctx))
+		return []NetCafe{{Name: "A店"}}, nil
+	}}}
+	cache, _ := newTestCache(t, time.Hour)
+
+	// -refresh のときだけ HTTP キャッシュの期間内のページも再検証させる
+	for _, refresh := range []bool{false, true} {
+		if _, err := cache.ScrapeAll(context.Background(), scraper, refresh); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if len(revalidated) != 2 || revalidated[0] || !revalidated[1] {
+		t.Errorf("expected only the refresh to revalidate, got %v", revalidated)
+	}
+}
+
+func TestSnapshotCache_StaleFallback(t *testing.T) {
+	var callsA, callsB atomic.Int32
+	var errA, errB error
+	scraper := NewScraper()
+	scraper.sources = []Source{
+		countingSource("a", &callsA, []NetCafe{{Name: "A店"}}, &errA),
+		countingSource("b", &callsB, []NetCafe{{Name: "B店"}}, &errB),
+	}
+
+	cache, clock := newTestCache(t, time.Hour)
+	if _, err := cache.ScrapeAll(context.Background(), scraper, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// ネットワークが落ちていても期限切れのキャッシュで代替する
+	*clock = clock.Add(24 * time.Hour)
+	errA = errors.New("network is unreachable")
+	errB = errA
+	result, err := cache.ScrapeAll(context.Background(), scraper, false)
+
+	var partial *PartialScrapeError
+	if !errors.As(err, &partial) || !partial.AllFailed() {
+		t.Fatalf("expected all sources to be reported as failed, got %v", err)
+	}
+	if len(result.Stores) != 2 {
+		t.Fatalf("expected stale stores, got %+v", result.Stores)
+	}
+	for _, r := range result.Sources {
+		if !r.Cached || r.OK() || r.Count != 1 {
+			t.Errorf("expected stale cached result with error, got %+v", r)
+		}
+	}
+}
+
+func TestSnapshotCache_SourceTTL(t *testing.T) {
+	var callsA, callsB atomic.Int32
+	var noErr error
+	scraper := NewScraper()
+	scraper.sources = []Source{
+		countingSource("a", &callsA, []NetCafe{{Name: "A店"}}, &noErr),
+		countingSource("b", &callsB, []NetCafe{{Name: "B店"}}, &noErr),
+	}
+
+	cache, clock := newTestCache(t, time.Hour)
+	cache.sourceTTL = map[string]time.Duration{"b": 10 * time.Minute}
+	if _, err := cache.ScrapeAll(context.Background(), scraper, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	*clock = clock.Add(20 * time.Minute)
+	result, err := cache.ScrapeAll(context.Background(), scraper, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if callsA.Load() != 1 || callsB.Load() != 2 {
+		t.Errorf("expected only b to be scraped again, a=%d b=%d", callsA.Load(), callsB.Load())
+	}
+	if !result.Sources[0].Cached || result.Sources[1].Cached {
+		t.Errorf("unexpected cached flags: %+v", result.Sources)
+	}
+	// 取得元の登録順は保たれる
+	if result.Stores[0].Name != "A店" || result.Stores[1].Name != "B店" {
+		t.Errorf("unexpected store order: %+v", result.Stores)
+	}
+}
+
+func TestSnapshotCache_BaseURLMismatch(t *testing.T) {
+	var calls atomic.Int32
+	var noErr error
+	src := countingSource("a", &calls, []NetCafe{{Name: "A店"}}, &noErr)
+
+	cache, _ := newTestCache(t, time.Hour)
+	scraper := NewScraper()
+	scraper.sources = []Source{src}
+	if _, err := cache.ScrapeAll(context.Background(), scraper, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	other := NewScraper(WithBaseURL("a", "http://localhost:8080"))
+	other.sources = []Source{src}
+	if _, err := cache.ScrapeAll(context.Background(), other, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("cache for another base URL should not be used, calls=%d", calls.Load())
+	}
+}
+
+func TestSnapshotCache_CorruptFile(t *testing.T) {
+	var calls atomic.Int32
+	var noErr error
+	scraper := NewScraper()
+	scraper.sources = []Source{countingSource("a", &calls, []NetCafe{{Name: "A店"}}, &noErr)}
+
+	cache, _ := newTestCache(t, time.Hour)
+	os.MkdirAll(filepath.Dir(cache.path), 0o755)
+	if err := os.WriteFile(cache.path, []byte("{broken"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := cache.ScrapeAll(context.Background(), scraper, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls.Load() != 1 || len(result.Stores) != 1 {
+		t.Errorf("expected corrupt cache to be ignored, calls=%d stores=%d", calls.Load(), len(result.Stores))
+	}
+	if _, err := cache.load(); err != nil {
+		t.Errorf("expected corrupt cache to be overwritten: %v", err)
+	}
+}
+
+func TestCacheTTLFlag(t *testing.T) {
+	f := cacheTTLFlag{ttl: defaultCacheTTL}
+
+	if err := f.Set("2h"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := f.Set("kaikatsu=30m"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, invalid := range []string{"soon", "=1h", "kaikatsu=", "-1h"} {
+		if err := f.Set(invalid); err == nil {
+			t.Errorf("expected error for %q", invalid)
+		}
+	}
+
+	cache := NewSnapshotCache("", f.ttl, f.sourceTTL)
+	if got := cache.TTL("kaikatsu"); got != 30*time.Minute {
+		t.Errorf("expected per-source TTL, got %v", got)
+	}
+	if got := cache.TTL("jiqoo"); got != 2*time.Hour {
+		t.Errorf("expected default TTL, got %v", got)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Chain は店舗のチェーン（ブランド）。ID は取得元の ChainID、Name は表示名。
+type Chain struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+func (c Chain) String() string { return c.Name }
+
+// sourceChain は取得元 src のチェーン。
+func sourceChain(src Source) Chain {
+	return Chain{ID: src.ChainID(), Name: src.Name()}
+}
+
+// matches は term がチェーンID か表示名（全角・半角、大文字・小文字を区別しない）に一致するかを返す。
+func (c Chain) matches(term string) bool {
+	term = strings.ToLower(strings.TrimSpace(normalizeWidth(term)))
+	return term != "" && (term == c.ID || term == strings.ToLower(normalizeWidth(c.Name)))
+}
+
+// trimChainName は店名の先頭にあるチェーン名（「快活CLUB 新宿西口店」の「快活CLUB」）を取り除く。
+// 取り除くと何も残らない場合はそのまま返す。
+func trimChainName(name string, chain Chain) string {
+	prefix := []rune(normalizeWidth(chain.Name))
+	runes := []rune(name)
+	if len(prefix) == 0 || len(runes) <= len(prefix) {
+		return name
+	}
+	// normalizeWidth は1文字ずつ置き換えるので、文字数は変わらない
+	if normalizeWidth(string(runes[:len(prefix)])) != string(prefix) {
+		return name
+	}
+	// 「A1店」の「A」のように英数字の途中で切れる場合はチェーン名とみなさない
+	if isASCIIAlnum(prefix[len(prefix)-1]) && isASCIIAlnum(runes[len(prefix)]) {
+		return name
+	}
+	if rest := strings.TrimLeft(string(runes[len(prefix):]), " 　・"); rest != "" {
+		return rest
+	}
+	return name
+}
+
+func isASCIIAlnum(r rune) bool {
+	return r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+}
+
+// setChain はチェーンが分からない店舗に chain を設定し、店名からチェーン名を取り除く。
+func (c *NetCafe) setChain(chain Chain) {
+	if c.Chain.ID == "" {
+		c.Chain = chain
+	}
+	c.Name = trimChainName(c.Name, c.Chain)
+}
+
+// FullName はチェーン名を付けた店名（例: 快活CLUB 新宿西口店）。
+func (c NetCafe) FullName() string {
+	if c.Chain.Name == "" || strings.Contains(c.Name, c.Chain.Name) {
+		return c.Name
+	}
+	return c.Chain.Name + " " + c.Name
+}
+
+// parseChains は -chain の「kaikatsu,自遊空間」のような指定を解析し、chains のうち一致するチェーンの ID を返す。
+func parseChains(spec string, chains []Chain) ([]string, error) {
+	var ids []string
+	for _, term := range strings.Split(spec, ",") {
+		if strings.TrimSpace(term) == "" {
+			continue
+		}
+		found := false
+		for _, c := range chains {
+			if c.matches(term) {
+				ids = append(ids, c.ID)
+				found = true
+				break
+			}
+		}
+		if !found {
+			names := make([]string, len(chains))
+			for i, c := range chains {
+				names[i] = c.ID
+			}
+			return nil, fmt.Errorf("unknown chain %q (available: %s)", strings.TrimSpace(term), strings.Join(names, ", "))
+		}
+	}
+	return ids, nil
+}
+
+// filterByChain は terms（チェーンID または表示名）のいずれかのチェーンの店舗だけを返す。
+func filterByChain(cafes []NetCafe, terms []string) []NetCafe {
+	var results []NetCafe
+	for _, cafe := range cafes {
+		for _, term := range terms {
+			if cafe.Chain.matches(term) {
+				results = append(results, cafe)
+				break
+			}
+		}
+	}
+	return results
+}
+
+// groupByChain は店舗をチェーンごとにまとめる。チェーンの順序は最初に現れた順で、チェーン内の順序は保つ。
+func groupByChain(cafes []NetCafe) []NetCafe {
+	var order []string
+	groups := make(map[string][]NetCafe)
+	for _, cafe := range cafes {
+		if _, ok := groups[cafe.Chain.ID]; !ok {
+			order = append(order, cafe.Chain.ID)
+		}
+		groups[cafe.Chain.ID] = append(groups[cafe.Chain.ID], cafe)
+	}
+	results := make([]NetCafe, 0, len(cafes))
+	for _, id := range order {
+		results = append(results, groups[id]...)
+	}
+	return results
+}
+
+// chains は s の取得元のチェーンを優先順に返す。
+func (s *Scraper) chains() []Chain {
+	chains := make([]Chain, len(s.sources))
+	for i, src := range s.sources {
+		chains[i] = sourceChain(src)
+	}
+	return chains
+}
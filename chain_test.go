@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var kaikatsuChain = Chain{ID: "kaikatsu", Name: "快活CLUB"}
+
+func TestTrimChainName(t *testing.T) {
+	tests := []struct {
+		name     string
+		chain    Chain
+		expected string
+	}{
+		{"快活CLUB 新宿西口店", kaikatsuChain, "新宿西口店"},
+		{"快活CLUB新宿西口店", kaikatsuChain, "新宿西口店"},
+		{"快活ＣＬＵＢ　新宿西口店", kaikatsuChain, "新宿西口店"},
+		{"新宿西口店", kaikatsuChain, "新宿西口店"},
+		{"快活CLUB", kaikatsuChain, "快活CLUB"},
+		{"DiCE 秋葉原店", Chain{ID: "dice", Name: "DiCE"}, "秋葉原店"},
+		{"DiCEX 秋葉原店", Chain{ID: "dice", Name: "DiCE"}, "DiCEX 秋葉原店"},
+		{"A1店", Chain{ID: "a", Name: "A"}, "A1店"},
+		{"新宿店", Chain{}, "新宿店"},
+	}
+	for _, tt := range tests {
+		if got := trimChainName(tt.name, tt.chain); got != tt.expected {
+			t.Errorf("trimChainName(%q, %q) = %q, expected %q", tt.name, tt.chain.Name, got, tt.expected)
+		}
+	}
+}
+
+func TestNetCafe_FullName(t *testing.T) {
+	tests := []struct {
+		cafe     NetCafe
+		expected string
+	}{
+		{NetCafe{Name: "新宿西口店", Chain: kaikatsuChain}, "快活CLUB 新宿西口店"},
+		{NetCafe{Name: "快活CLUB 新宿西口店", Chain: kaikatsuChain}, "快活CLUB 新宿西口店"},
+		{NetCafe{Name: "新宿西口店"}, "新宿西口店"},
+	}
+	for _, tt := range tests {
+		if got := tt.cafe.FullName(); got != tt.expected {
+			t.Errorf("FullName(%+v) = %q, expected %q", tt.cafe, got, tt.expected)
+		}
+	}
+}
+
+func TestParseChains(t *testing.T) {
+	chains := NewScraper().chains()
+
+	ids, err := parseChains("kaikatsu, 自遊空間,ＤｉＣＥ", chains)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(ids, []string{"kaikatsu", "jiqoo", "dice"}) {
+		t.Errorf("unexpected chain IDs: %v", ids)
+	}
+
+	if ids, err := parseChains("", chains); err != nil || ids != nil {
+		t.Errorf("expected no chains for empty spec, got %v, %v", ids, err)
+	}
+	if _, err := parseChains("kaikatsu,unknown", chains); err == nil || !strings.Contains(err.Error(), "unknown") {
+		t.Errorf("expected unknown chain error, got %v", err)
+	}
+}
+
+func TestFilterByChain(t *testing.T) {
+	stores := getSampleStores()
+
+	got := filterByChain(stores, []string{"kaikatsu", "マンボー"})
+	if len(got) != 2 || got[0].Chain.ID != "kaikatsu" || got[1].Chain.ID != "manboo" {
+		t.Errorf("unexpected stores: %+v", got)
+	}
+	if got := filterByChain(stores, []string{"unknown"}); len(got) != 0 {
+		t.Errorf("expected no stores, got %+v", got)
+	}
+}
+
+func TestSampleStores_Chain(t *testing.T) {
+	for _, s := range getSampleStores() {
+		if s.Chain.ID == "" || s.Chain.Name == "" || strings.HasPrefix(s.Name, s.Chain.Name) {
+			t.Errorf("sample store should have a chain and a name without it: %+v", s)
+		}
+	}
+}
+
+func TestWriteCafes_TextGroupedByChain(t *testing.T) {
+	cafes := []NetCafe{
+		{Name: "新宿西口店", Chain: kaikatsuChain},
+		{Name: "渋谷宮益坂店", Chain: Chain{ID: "manboo", Name: "マンボー"}},
+		{Name: "池袋店", Chain: kaikatsuChain},
+		{Name: "個人店"},
+	}
+	var buf bytes.Buffer
+	if err := writeCafes(&buf, formatText, cafes, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := buf.String()
+
+	var order []string
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(line, "■ ") || strings.HasPrefix(line, "店舗名: ") {
+			order = append(order, line)
+		}
+	}
+	expected := []string{
+		"■ 快活CLUB (2店舗)",
+		"店舗名: 快活CLUB 新宿西口店",
+		"店舗名: 快活CLUB 池袋店",
+		"■ マンボー (1店舗)",
+		"店舗名: マンボー 渋谷宮益坂店",
+		"■ その他 (1店舗)",
+		"店舗名: 個人店",
+	}
+	if !reflect.DeepEqual(order, expected) {
+		t.Errorf("unexpected text output:\n%s", out)
+	}
+}
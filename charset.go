@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/transform"
+)
+
+// sniffLen は文字コードの判定に使う本文の先頭のバイト数。
+const sniffLen = 4096
+
+// decodeHTML は HTML の本文 r を UTF-8 に変換するリーダーを返す。
+// 文字コードは BOM、Content-Type の charset、<meta charset> の順に判定し、
+// どれも指定がなければ本文から UTF-8・Shift_JIS・EUC-JP を推定する。
+func decodeHTML(r io.Reader, contentType string) io.Reader {
+	br := bufio.NewReaderSize(r, sniffLen)
+	head, _ := br.Peek(sniffLen)
+	enc, name := detectCharset(head, contentType)
+	var decoded io.Reader = br
+	if name != "utf-8" {
+		decoded = transform.NewReader(br, enc.NewDecoder())
+	}
+
+	// BOM は本文として扱われてしまうので読み飛ばす
+	out := bufio.NewReader(decoded)
+	if r, _, err := out.ReadRune(); err == nil && r != '\ufeff' {
+		out.UnreadRune()
+	}
+	return out
+}
+
+// detectCharset は head（本文の先頭）と contentType から文字コードを判定し、その名前とともに返す。
+func detectCharset(head []byte, contentType string) (encoding.Encoding, string) {
+	enc, name, certain := charset.DetermineEncoding(head, contentType)
+	if certain {
+		return enc, name
+	}
+	// DetermineEncoding は指定がなく UTF-8 として不正なら windows-1252 とみなすが、
+	// 日本語のページではまず Shift_JIS か EUC-JP なので本文から推定する
+	if name == "windows-1252" {
+		if enc, name, ok := sniffJapanese(head); ok {
+			return enc, name
+		}
+	}
+	return enc, name
+}
+
+// sniffJapanese は head を Shift_JIS と EUC-JP で復号してみて、不正なバイト列がなく
+// かな・漢字の多い方を返す。どちらとしても不正なら ok は false。
+func sniffJapanese(head []byte) (enc encoding.Encoding, name string, ok bool) {
+	candidates := []struct {
+		enc  encoding.Encoding
+		name string
+	}{
+		{japanese.ShiftJIS, "shift_jis"},
+		{japanese.EUCJP, "euc-jp"},
+	}
+	best := -1
+	for _, c := range candidates {
+		score, valid := japaneseScore(head, c.enc)
+		if valid && score > best {
+			enc, name, best = c.enc, c.name, score
+		}
+	}
+	return enc, name, best > 0
+}
+
+// japaneseScore は b を enc で復号したときの全角のかな・漢字の数を返す。
+// 末尾で切れた文字を除き、復号できないバイト列があれば valid は false。
+func japaneseScore(b []byte, enc encoding.Encoding) (score int, valid bool) {
+	decoded, _, err := transform.Bytes(enc.NewDecoder(), b)
+	if err != nil {
+		return 0, false
+	}
+	s := string(decoded)
+	if len(b) == sniffLen {
+		// 先頭だけを読んだので、最後の1文字は途中で切れているかもしれない
+		_, size := utf8.DecodeLastRuneInString(s)
+		s = s[:len(s)-size]
+	}
+	for _, r := range s {
+		switch {
+		case r == utf8.RuneError:
+			return 0, false
+		case r >= '\uff61' && r <= '\uff9f':
+			// 半角カナは EUC-JP を Shift_JIS として読んだときにも現れるので数えない
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana, unicode.Han):
+			score++
+		}
+	}
+	return score, true
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// encodeJapanese は s を enc で符号化したバイト列を返す。
+func encodeJapanese(t *testing.T, enc encoding.Encoding, s string) []byte {
+	t.Helper()
+	b, err := enc.NewEncoder().Bytes([]byte(s))
+	if err != nil {
+		t.Fatalf("failed to encode fixture: %v", err)
+	}
+	return b
+}
+
+func TestDecodeHTML(t *testing.T) {
+	const body = `<html><head>%s</head><body><h3 class="shop-name">新宿西口店</h3><p>東京都新宿区西新宿１－１２－９　営業時間：２４時間</p></body></html>`
+	tests := []struct {
+		name        string
+		enc         encoding.Encoding
+		contentType string
+		meta        string
+	}{
+		{"Content-Type Shift_JIS", japanese.ShiftJIS, "text/html; charset=Shift_JIS", ""},
+		{"Content-Type EUC-JP", japanese.EUCJP, "text/html; charset=EUC-JP", ""},
+		{"meta charset", japanese.ShiftJIS, "text/html", `<meta charset="shift_jis">`},
+		{"meta http-equiv", japanese.EUCJP, "text/html", `<meta http-equiv="Content-Type" content="text/html; charset=euc-jp">`},
+		{"sniffed Shift_JIS", japanese.ShiftJIS, "text/html", ""},
+		{"sniffed EUC-JP", japanese.EUCJP, "", ""},
+		{"UTF-8", encoding.Nop, "text/html", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := strings.Replace(body, "%s", tt.meta, 1)
+			raw := encodeJapanese(t, tt.enc, want)
+			got, err := io.ReadAll(decodeHTML(strings.NewReader(string(raw)), tt.contentType))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != want {
+				t.Errorf("unexpected decoded body:\n%s", got)
+			}
+		})
+	}
+}
+
+func TestDecodeHTML_BOM(t *testing.T) {
+	// BOM は Content-Type より優先する
+	const want = "<p>池袋店</p>"
+	for name, enc := range map[string]encoding.Encoding{
+		"UTF-8":    unicode.UTF8BOM,
+		"UTF-16LE": unicode.UTF16(unicode.LittleEndian, unicode.UseBOM),
+	} {
+		raw := encodeJapanese(t, enc, want)
+		got, err := io.ReadAll(decodeHTML(strings.NewReader(string(raw)), "text/html; charset=Shift_JIS"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("%s: unexpected decoded body: %q", name, got)
+		}
+	}
+}
+
+func TestDetectCharset_LongDocument(t *testing.T) {
+	// 判定に使う先頭部分の境界で2バイト文字が切れても推定できる
+	body := "<p>" + strings.Repeat("ネットカフェ快活", sniffLen/8) + "</p>"
+	for _, tt := range []struct {
+		enc  encoding.Encoding
+		name string
+	}{
+		{japanese.ShiftJIS, "shift_jis"},
+		{japanese.EUCJP, "euc-jp"},
+	} {
+		raw := encodeJapanese(t, tt.enc, body)
+		for _, offset := range []int{0, 1} {
+			head := raw[offset : offset+sniffLen]
+			if _, name := detectCharset(head, ""); name != tt.name {
+				t.Errorf("detectCharset(offset %d) = %s, expected %s", offset, name, tt.name)
+			}
+		}
+	}
+}
+
+func TestScraper_ScrapeKaikatsu_ShiftJIS(t *testing.T) {
+	htmlContent := `
+	<html>
+	<head><meta http-equiv="Content-Type" content="text/html; charset=Shift_JIS"></head>
+	<body>
+		<div class="shop-list-item">
+			<h3 class="shop-name">新宿西口店</h3>
+			<div class="shop-address">東京都新宿区西新宿1-12-9</div>
+		</div>
+	</body>
+	</html>
+	`
+	raw := encodeJapanese(t, japanese.ShiftJIS, htmlContent)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write(raw)
+	}))
+	defer server.Close()
+
+	scraper := NewScraper(WithBaseURL("kaikatsu", server.URL), WithTransport(http.DefaultTransport))
+	cafes, err := builtinSource(t, "kaikatsu").Scrape(context.Background(), scraper)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cafes) != 1 || cafes[0].Name != "新宿西口店" || cafes[0].Location != "東京都新宿区西新宿1-12-9" {
+		t.Errorf("expected Shift_JIS page to be decoded, got %+v", cafes)
+	}
+}
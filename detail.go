@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Price は料金表の1行（例: 3時間パック 1200円）。
+type Price struct {
+	Plan string `json:"plan"`
+	Yen  int    `json:"yen"`
+}
+
+func (p Price) String() string {
+	return fmt.Sprintf("%s %d円", p.Plan, p.Yen)
+}
+
+// StoreDetail は店舗の詳細ページから読み取った情報。空のフィールドは一覧の値を残す。
+type StoreDetail struct {
+	Location  string
+	Hours     string
+	Phone     string
+	Geo       *GeoPoint
+	Amenities []string
+	Prices    []Price
+}
+
+// DetailParser は店舗の詳細ページを解析できる取得元が実装する。
+// WithDetails を指定すると、ScrapeAll は一覧で得た各店舗の URL をたどってこれを呼ぶ。
+type DetailParser interface {
+	ParseDetail(doc *goquery.Document) StoreDetail
+}
+
+// WithDetails は一覧の取得後に各店舗の詳細ページを取得し、n 件ずつ並行して解析する。
+func WithDetails(n int) ScraperOption {
+	return func(s *Scraper) {
+		s.detailConcurrency = max(n, 0)
+	}
+}
+
+func (c *NetCafe) mergeDetail(d StoreDetail) {
+	if d.Location != "" {
+		c.Location = d.Location
+	}
+	if d.Hours != "" {
+		c.Hours = d.Hours
+	}
+	if d.Phone != "" {
+		c.Phone = d.Phone
+	}
+	if d.Geo != nil {
+		c.Geo = d.Geo
+	}
+	if len(d.Amenities) > 0 {
+		c.Amenities = d.Amenities
+	}
+	if len(d.Prices) > 0 {
+		c.Prices = d.Prices
+	}
+}
+
+// DetailError は一部の詳細ページを取得できなかったことを表す。該当する店舗は一覧の情報のまま残る。
+type DetailError struct {
+	Failed int
+	Total  int
+	// Err は最初に失敗した詳細ページのエラー。
+	Err error
+}
+
+func (e *DetailError) Error() string {
+	return fmt.Sprintf("%d of %d detail pages failed: %v", e.Failed, e.Total, e.Err)
+}
+
+func (e *DetailError) Unwrap() error {
+	return e.Err
+}
+
+// detailWorkers は詳細ページを同時に取得する数。ホストごとのレート制限があれば、
+// 制限を待つだけの取得が並ばないよう1秒あたりのリクエスト数までに抑える。
+func (s *Scraper) detailWorkers() int {
+	n := s.detailConcurrency
+	if s.rate > 0 {
+		n = min(n, max(1, int(math.Ceil(s.rate))))
+	}
+	return n
+}
+
+// scrapeDetails は cafes のうち詳細ページを持つ店舗を並行して取得し、その場で書き換える。
+// 詳細ページの取得に失敗した店舗は一覧の情報のまま残す。解析できた件数と、失敗があれば *DetailError を返す。
+func (s *Scraper) scrapeDetails(ctx context.Context, src Source, parser DetailParser, cafes []NetCafe) (int, error) {
+	base := s.baseURL(src)
+	sem := make(chan struct{}, s.detailWorkers())
+	var wg sync.WaitGroup
+	var parsed atomic.Int32
+
+	var mu sync.Mutex
+	detailErr := &DetailError{}
+	fail := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		detailErr.Failed++
+		if detailErr.Err == nil {
+			detailErr.Err = err
+		}
+	}
+
+	for i := range cafes {
+		if !isDetailURL(base, cafes[i].URL) {
+			continue
+		}
+		detailErr.Total++
+		wg.Add(1)
+		go func(c *NetCafe) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				fail(ctx.Err())
+				return
+			}
+
+			doc, err := s.fetchDocument(ctx, c.URL)
+			if err != nil {
+				log.Printf("Error scraping detail page %s: %v", c.URL, err)
+				fail(fmt.Errorf("%s: %w", c.URL, err))
+				return
+			}
+			// 構造化データがあればそれを優先し、足りない項目だけセレクターで補う
+			c.mergeDetail(parser.ParseDetail(doc))
+			if d, ok := structuredDetail(doc, base); ok {
+				c.mergeDetail(d)
+			}
+			parsed.Add(1)
+		}(&cafes[i])
+	}
+	wg.Wait()
+	if detailErr.Failed > 0 {
+		return int(parsed.Load()), detailErr
+	}
+	return int(parsed.Load()), nil
+}
+
+// isDetailURL は u が取得元のトップページ以外のページを指しているかどうかを返す。
+func isDetailURL(base, u string) bool {
+	return strings.HasPrefix(u, base+"/") && u != base+"/"
+}
+
+var pricePattern = regexp.MustCompile(`([\d,]+)\s*円`)
+
+func parseDetail(doc *goquery.Document, sel DetailSpec) StoreDetail {
+	d := StoreDetail{
+		Location: firstText(doc, sel.Location, "住所", "所在地"),
+		Hours:    firstText(doc, sel.Hours, "営業時間"),
+		Phone:    firstText(doc, sel.Phone, "電話番号", "TEL"),
+	}
+
+	doc.Find(sel.Amenities).Each(func(i int, s *goquery.Selection) {
+		if text := strings.TrimSpace(s.Text()); text != "" {
+			d.Amenities = append(d.Amenities, text)
+		}
+	})
+
+	doc.Find(sel.Prices).Each(func(i int, s *goquery.Selection) {
+		if p, ok := parsePrice(s); ok {
+			d.Prices = append(d.Prices, p)
+		}
+	})
+	return d
+}
+
+// firstText は selector に一致する最初の要素、なければ見出しが labels のいずれかで始まる
+// dt に続く dd のテキストを返す。
+func firstText(doc *goquery.Document, selector string, labels ...string) string {
+	if text := strings.TrimSpace(doc.Find(selector).First().Text()); text != "" {
+		return text
+	}
+	var text string
+	doc.Find("dt, th").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		label := strings.TrimSpace(s.Text())
+		for _, l := range labels {
+			if strings.HasPrefix(label, l) {
+				text = strings.TrimSpace(s.NextFiltered("dd, td").Text())
+				return text == ""
+			}
+		}
+		return true
+	})
+	return text
+}
+
+// parsePrice は「3時間パック 1,200円」のような行、または「<th>3時間パック</th><td>1,200円</td>」
+// のような表の行を解析する。
+func parsePrice(s *goquery.Selection) (Price, bool) {
+	var plan, amount string
+	if cells := s.Children().Filter("th, td, dt, dd, span"); cells.Length() >= 2 {
+		plan = cells.First().Text()
+		amount = cells.Last().Text()
+	} else {
+		amount = s.Text()
+	}
+
+	amount = normalizeWidth(amount)
+	m := pricePattern.FindStringSubmatchIndex(amount)
+	if m == nil {
+		return Price{}, false
+	}
+	yen, err := strconv.Atoi(strings.ReplaceAll(amount[m[2]:m[3]], ",", ""))
+	if err != nil {
+		return Price{}, false
+	}
+	if plan == "" {
+		plan = amount[:m[0]]
+	}
+	plan = strings.TrimSpace(strings.TrimRight(strings.TrimSpace(normalizeWidth(plan)), ":："))
+	if plan == "" {
+		return Price{}, false
+	}
+	return Price{Plan: plan, Yen: yen}, true
+}
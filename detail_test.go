@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestScraper_ScrapeAll_Details(t *testing.T) {
+	listHTML := `
+	<html><body>
+		<div class="shop-list-item">
+			<h3 class="shop-name">新宿西口店</h3>
+			<div class="shop-address">東京都新宿区西新宿1-12-9</div>
+			<a href="/shop/shinjuku/">詳細</a>
+		</div>
+		<div class="shop-list-item">
+			<h3 class="shop-name">池袋店</h3>
+			<div class="shop-address">東京都豊島区西池袋1-1-1</div>
+			<a href="/shop/ikebukuro/">詳細</a>
+		</div>
+	</body></html>
+	`
+	detailHTML := `
+	<html><body>
+		<div class="shop-detail">
+			<dl>
+				<dt>営業時間</dt><dd>10:00〜翌5:00</dd>
+				<dt>電話番号</dt><dd>TEL：０３－５３２１－６１６６</dd>
+			</dl>
+		</div>
+		<ul class="shop-facility"><li>シャワー</li><li>Wi-Fi</li><li> </li></ul>
+		<table class="price-table">
+			<tr><th>30分</th><td>２８６円</td></tr>
+			<tr><th>3時間パック</th><td>1,200円（税込）</td></tr>
+			<tr><th>備考</th><td>会員登録が必要です</td></tr>
+		</table>
+	</body></html>
+	`
+
+	var detailRequests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/shop/tokyo/":
+			w.Write([]byte(listHTML))
+		case "/shop/shinjuku/":
+			detailRequests.Add(1)
+			w.Write([]byte(detailHTML))
+		default:
+			detailRequests.Add(1)
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	scraper := NewScraper(WithBaseURL("kaikatsu", server.URL), WithTransport(http.DefaultTransport), WithDetails(2))
+	scraper.sources = []Source{builtinSource(t, "kaikatsu")}
+
+	// 詳細ページの一部が取得できなければ、店舗は残したまま取得元の不完全な結果として報告する
+	result, err := scraper.ScrapeAll(context.Background())
+	var partial *PartialScrapeError
+	if !errors.As(err, &partial) || partial.AllFailed() || len(partial.Incomplete) != 1 {
+		t.Fatalf("expected an incomplete source, got %v", err)
+	}
+	var detailErr *DetailError
+	if !errors.As(result.Sources[0].DetailErr, &detailErr) || detailErr.Failed != 1 || detailErr.Total != 2 {
+		t.Errorf("unexpected detail error: %v", result.Sources[0].DetailErr)
+	}
+	if detailRequests.Load() != 2 || result.Sources[0].Details != 1 {
+		t.Errorf("expected 2 detail requests with 1 parsed, got %d requests, %d parsed",
+			detailRequests.Load(), result.Sources[0].Details)
+	}
+
+	byName := make(map[string]NetCafe)
+	for _, c := range result.Stores {
+		byName[c.Name] = c
+	}
+
+	shinjuku := byName["新宿西口店"]
+	if shinjuku.Hours != "10:00〜翌5:00" || shinjuku.Phone != "03-5321-6166" || shinjuku.PhoneE164 != "+81353216166" {
+		t.Errorf("detail page was not merged: %+v", shinjuku)
+	}
+	if !reflect.DeepEqual(shinjuku.Amenities, []string{"シャワー", "Wi-Fi"}) {
+		t.Errorf("unexpected amenities: %v", shinjuku.Amenities)
+	}
+	expectedPrices := []Price{{Plan: "30分", Yen: 286}, {Plan: "3時間パック", Yen: 1200}}
+	if !reflect.DeepEqual(shinjuku.Prices, expectedPrices) {
+		t.Errorf("unexpected prices: %v", shinjuku.Prices)
+	}
+
+	// 詳細ページが取得できなかった店舗は一覧の情報のまま
+	ikebukuro := byName["池袋店"]
+	if ikebukuro.Hours != "24時間営業" || ikebukuro.Location != "東京都豊島区西池袋1-1-1" || ikebukuro.Prices != nil {
+		t.Errorf("list data should be kept when the detail page fails: %+v", ikebukuro)
+	}
+}
+
+func TestScraper_ScrapeAll_DetailsDisabled(t *testing.T) {
+	var detailRequests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/shop/tokyo/" {
+			detailRequests.Add(1)
+		}
+		w.Write([]byte(`<div class="shop-list-item"><h3>新宿西口店</h3><a href="/shop/shinjuku/">詳細</a></div>`))
+	}))
+	defer server.Close()
+
+	scraper := NewScraper(WithBaseURL("kaikatsu", server.URL), WithTransport(http.DefaultTransport))
+	scraper.sources = []Source{builtinSource(t, "kaikatsu")}
+	if _, err := scraper.ScrapeAll(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if detailRequests.Load() != 0 {
+		t.Errorf("detail pages should not be fetched by default, got %d requests", detailRequests.Load())
+	}
+}
+
+func TestScraper_ScrapeDetails_Concurrency(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+	var mu sync.Mutex
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		mu.Lock()
+		if n > maxInFlight.Load() {
+			maxInFlight.Store(n)
+		}
+		mu.Unlock()
+		<-release
+		w.Write([]byte(`<div class="shop-detail"><div class="shop-hours">10:00〜22:00</div></div>`))
+	}))
+	defer server.Close()
+
+	var cafes []NetCafe
+	for _, path := range []string{"/a/", "/b/", "/c/", "/d/", "/e/"} {
+		cafes = append(cafes, NetCafe{Name: path, URL: server.URL + path})
+	}
+	scraper := NewScraper(WithBaseURL("kaikatsu", server.URL), WithTransport(http.DefaultTransport), WithDetails(2))
+
+	src := builtinSource(t, "kaikatsu")
+	done := make(chan int)
+	go func() {
+		parsed, err := scraper.scrapeDetails(context.Background(), src, src, cafes)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		done <- parsed
+	}()
+	for i := 0; i < len(cafes); i++ {
+		release <- struct{}{}
+	}
+	if parsed := <-done; parsed != len(cafes) {
+		t.Errorf("expected %d parsed, got %d", len(cafes), parsed)
+	}
+	if maxInFlight.Load() > 2 {
+		t.Errorf("expected at most 2 concurrent detail requests, got %d", maxInFlight.Load())
+	}
+	for _, c := range cafes {
+		if c.Hours != "10:00〜22:00" {
+			t.Errorf("unexpected hours for %s: %q", c.Name, c.Hours)
+		}
+	}
+}
+
+func TestParsePrice(t *testing.T) {
+	tests := []struct {
+		html     string
+		expected Price
+		ok       bool
+	}{
+		{`<li>3時間パック 1,200円</li>`, Price{Plan: "3時間パック", Yen: 1200}, true},
+		{`<li>ナイトパック（8時間）：１，８００円</li>`, Price{Plan: "ナイトパック(8時間)", Yen: 1800}, true},
+		{`<tr><th>基本料金 30分</th><td>286円</td></tr>`, Price{Plan: "基本料金 30分", Yen: 286}, true},
+		{`<li>1,200円</li>`, Price{}, false},
+		{`<li>お問い合わせください</li>`, Price{}, false},
+	}
+
+	for _, tt := range tests {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader("<table>" + tt.html + "</table><ul>" + tt.html + "</ul>"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, ok := parsePrice(doc.Find("li, tr").First())
+		if got != tt.expected || ok != tt.ok {
+			t.Errorf("parsePrice(%s) = %+v, %v; expected %+v, %v", tt.html, got, ok, tt.expected, tt.ok)
+		}
+	}
+}
+
+func TestIsDetailURL(t *testing.T) {
+	base := "https://www.kaikatsu.jp"
+	tests := map[string]bool{
+		base + "/shop/shinjuku/":          true,
+		base + "/":                        false,
+		"":                                false,
+		"https://example.com/shop/other/": false,
+	}
+	for u, expected := range tests {
+		if got := isDetailURL(base, u); got != expected {
+			t.Errorf("isDetailURL(%q) = %v, expected %v", u, got, expected)
+		}
+	}
+}
+
+func TestScraper_DetailWorkers(t *testing.T) {
+	tests := []struct {
+		opts     []ScraperOption
+		expected int
+	}{
+		{[]ScraperOption{WithDetails(10)}, 1}, // 既定のレート制限（毎秒1回）
+		{[]ScraperOption{WithDetails(10), WithRateLimit(0.5)}, 1},
+		{[]ScraperOption{WithDetails(10), WithRateLimit(4)}, 4},
+		{[]ScraperOption{WithDetails(2), WithRateLimit(4)}, 2},
+		{[]ScraperOption{WithDetails(10), WithTransport(http.DefaultTransport)}, 10}, // レート制限なし
+	}
+	for _, tt := range tests {
+		if got := NewScraper(tt.opts...).detailWorkers(); got != tt.expected {
+			t.Errorf("detailWorkers() = %d, expected %d (rate %v)", got, tt.expected, NewScraper(tt.opts...).rate)
+		}
+	}
+}
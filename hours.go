@@ -0,0 +1,340 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// jst は営業時間を評価するタイムゾーン。日本は夏時間がないので固定オフセットで足りる。
+var jst = time.FixedZone("Asia/Tokyo", 9*60*60)
+
+const minutesPerDay = 24 * 60
+
+// TimeRange は0時からの経過分で表した営業時間帯。
+// End が minutesPerDay を超える場合は翌日にまたがる（例: 10:00〜翌5:00 は 600〜1740）。
+type TimeRange struct {
+	Start int
+	End   int
+}
+
+func (r TimeRange) String() string {
+	return fmt.Sprintf("%d:%02d-%d:%02d", r.Start/60, r.Start%60, r.End/60, r.End%60)
+}
+
+// HoursException は特定の日付の営業時間。Ranges が空ならその日は休業。
+type HoursException struct {
+	Month  time.Month
+	Day    int
+	Ranges []TimeRange
+}
+
+// OpeningHours は営業時間テキストを解析した結果。
+type OpeningHours struct {
+	// Open24h は毎日（祝日も）0:00〜24:00 で営業する場合に true。
+	Open24h bool
+	// Weekly は time.Weekday ごとの営業時間帯。空の曜日は休業。
+	Weekly [7][]TimeRange
+	// HasHoliday が true のとき、祝日は Weekly ではなく Holiday の時間帯で営業する。
+	HasHoliday bool
+	Holiday    []TimeRange
+	Exceptions []HoursException
+}
+
+var (
+	timeRangePattern = regexp.MustCompile(
+		`((?:[月火水木金土日祝](?:曜日?)?|平日|土日祝?|毎日|[・~,-])*)\s*[:]?\s*` +
+			`(\d{1,2})(?::(\d{2})|時(?:(\d{1,2})分)?)\s*[~-]\s*(翌)?\s*(\d{1,2})(?::(\d{2})|時(?:(\d{1,2})分)?)`)
+	closedDaysPattern = regexp.MustCompile(
+		`定休日?\s*[:]?\s*((?:[月火水木金土日祝](?:曜日?)?[・,]?)+)|((?:[月火水木金土日祝](?:曜日?)?[・,]?)+)\s*(?:定休|休み|休業)`)
+	exceptionPattern = regexp.MustCompile(
+		`(\d{1,2})/(\d{1,2})\s*(?:は)?\s*(?:(休業|休み|定休)|(\d{1,2}):(\d{2})\s*[~-]\s*(翌)?(\d{1,2}):(\d{2}))`)
+	weekdayChars = map[rune]time.Weekday{
+		'日': time.Sunday, '月': time.Monday, '火': time.Tuesday, '水': time.Wednesday,
+		'木': time.Thursday, '金': time.Friday, '土': time.Saturday,
+	}
+)
+
+// ParseOpeningHours は「24時間営業」「10:00〜翌5:00」「月〜金 10:00-22:00 / 土日祝 9:00-23:00」
+// 「水曜定休」「12/31 休業」のような営業時間テキストを解析する。
+func ParseOpeningHours(text string) (*OpeningHours, error) {
+	s := normalizeDashes(normalizeWidth(text))
+	h := &OpeningHours{}
+	found := false
+
+	if strings.Contains(s, "24時間") {
+		found = true
+		for d := range h.Weekly {
+			h.Weekly[d] = []TimeRange{{0, minutesPerDay}}
+		}
+	}
+
+	// 日付指定の例外は曜日指定の解析より先に取り除いておく
+	for _, m := range exceptionPattern.FindAllStringSubmatch(s, -1) {
+		found = true
+		month, _ := strconv.Atoi(m[1])
+		day, _ := strconv.Atoi(m[2])
+		ex := HoursException{Month: time.Month(month), Day: day}
+		if m[3] == "" {
+			ex.Ranges = []TimeRange{newTimeRange(m[4], m[5], m[6] != "", m[7], m[8])}
+		}
+		h.Exceptions = append(h.Exceptions, ex)
+	}
+	s = exceptionPattern.ReplaceAllString(s, " ")
+	if strings.Contains(s, "年末年始") && (strings.Contains(s, "休業") || strings.Contains(s, "休み")) {
+		found = true
+		for _, d := range []struct {
+			month time.Month
+			day   int
+		}{{time.December, 31}, {time.January, 1}, {time.January, 2}, {time.January, 3}} {
+			h.Exceptions = append(h.Exceptions, HoursException{Month: d.month, Day: d.day})
+		}
+		s = strings.ReplaceAll(s, "年末年始", " ")
+	}
+
+	var closedDays []time.Weekday
+	closedHoliday := false
+	for _, m := range closedDaysPattern.FindAllStringSubmatch(s, -1) {
+		found = true
+		days, holiday := parseDaySpec(m[1] + m[2])
+		closedDays = append(closedDays, days...)
+		closedHoliday = closedHoliday || holiday
+	}
+	s = closedDaysPattern.ReplaceAllString(s, " ")
+
+	var lastDays []time.Weekday
+	lastHoliday := false
+	assigned := [7]bool{}
+	for i, m := range timeRangePattern.FindAllStringSubmatch(s, -1) {
+		found = true
+		r := TimeRange{}
+		r.Start = clockMinutes(m[2], m[3]+m[4])
+		r.End = clockMinutes(m[6], m[7]+m[8])
+		if m[5] != "" || r.End <= r.Start {
+			r.End += minutesPerDay
+		}
+
+		days, holiday := parseDaySpec(m[1])
+		switch {
+		case len(days) == 0 && !holiday && i == 0:
+			days = allWeekdays()
+		case len(days) == 0 && !holiday:
+			days, holiday = lastDays, lastHoliday
+		}
+		lastDays, lastHoliday = days, holiday
+
+		for _, d := range days {
+			if !assigned[d] {
+				h.Weekly[d] = nil
+				assigned[d] = true
+			}
+			h.Weekly[d] = append(h.Weekly[d], r)
+		}
+		if holiday {
+			h.HasHoliday = true
+			h.Holiday = append(h.Holiday, r)
+		}
+	}
+
+	if !found {
+		return nil, fmt.Errorf("unrecognized opening hours: %q", text)
+	}
+
+	for _, d := range closedDays {
+		h.Weekly[d] = nil
+	}
+	if closedHoliday {
+		h.HasHoliday = true
+		h.Holiday = nil
+	}
+	h.Open24h = h.allDay()
+	return h, nil
+}
+
+// allDay はすべての曜日と祝日が 0:00〜24:00 の営業かどうかを返す。
+func (h *OpeningHours) allDay() bool {
+	fullDay := func(ranges []TimeRange) bool {
+		return len(ranges) == 1 && ranges[0] == TimeRange{0, minutesPerDay}
+	}
+	for _, ranges := range h.Weekly {
+		if !fullDay(ranges) {
+			return false
+		}
+	}
+	return !h.HasHoliday || fullDay(h.Holiday)
+}
+
+func newTimeRange(startHour, startMin string, nextDay bool, endHour, endMin string) TimeRange {
+	r := TimeRange{Start: clockMinutes(startHour, startMin), End: clockMinutes(endHour, endMin)}
+	if nextDay || r.End <= r.Start {
+		r.End += minutesPerDay
+	}
+	return r
+}
+
+func clockMinutes(hour, minute string) int {
+	h, _ := strconv.Atoi(hour)
+	m, _ := strconv.Atoi(minute)
+	return h*60 + m
+}
+
+func allWeekdays() []time.Weekday {
+	return []time.Weekday{time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday}
+}
+
+// parseDaySpec は「月~金」「土日祝」「平日」のような曜日指定を曜日の一覧と祝日の有無に変換する。
+func parseDaySpec(spec string) ([]time.Weekday, bool) {
+	spec = strings.NewReplacer("祝日", "祝", "曜日", "", "曜", "", "-", "~").Replace(spec)
+	var days []time.Weekday
+	holiday := false
+
+	if strings.Contains(spec, "毎日") {
+		return allWeekdays(), false
+	}
+	if strings.Contains(spec, "平日") {
+		days = append(days, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday)
+		spec = strings.ReplaceAll(spec, "平日", "")
+	}
+
+	runes := []rune(spec)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] == '祝' {
+			holiday = true
+			continue
+		}
+		d, ok := weekdayChars[runes[i]]
+		if !ok {
+			continue
+		}
+		if i+2 < len(runes) && runes[i+1] == '~' {
+			if end, ok := weekdayChars[runes[i+2]]; ok {
+				for w := d; ; w = (w + 1) % 7 {
+					days = append(days, w)
+					if w == end {
+						break
+					}
+				}
+				i += 2
+				continue
+			}
+		}
+		days = append(days, d)
+	}
+	return days, holiday
+}
+
+// IsOpenAt は t（Asia/Tokyo で評価）に営業しているかどうかを返す。
+// 前日から日付をまたいで営業している時間帯も考慮する。
+func (h *OpeningHours) IsOpenAt(t time.Time) bool {
+	local := t.In(jst)
+	minute := local.Hour()*60 + local.Minute()
+
+	for _, r := range h.rangesOn(local) {
+		if r.Start <= minute && minute < r.End {
+			return true
+		}
+	}
+	for _, r := range h.rangesOn(local.AddDate(0, 0, -1)) {
+		if r.End > minutesPerDay && r.Start <= minute+minutesPerDay && minute+minutesPerDay < r.End {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *OpeningHours) rangesOn(date time.Time) []TimeRange {
+	for _, ex := range h.Exceptions {
+		if ex.Month == date.Month() && ex.Day == date.Day() {
+			return ex.Ranges
+		}
+	}
+	if h.HasHoliday && isJapaneseHoliday(date) {
+		return h.Holiday
+	}
+	return h.Weekly[date.Weekday()]
+}
+
+// ParsedHours は Hours を解析した営業時間を返す。
+func (c NetCafe) ParsedHours() (*OpeningHours, error) {
+	return ParseOpeningHours(c.Hours)
+}
+
+// IsOpenAt は t に営業しているかどうかを返す。営業時間を解析できない店舗は false。
+func (c NetCafe) IsOpenAt(t time.Time) bool {
+	h, err := c.ParsedHours()
+	if err != nil {
+		return false
+	}
+	return h.IsOpenAt(t)
+}
+
+func filterOpenAt(cafes []NetCafe, t time.Time) []NetCafe {
+	var results []NetCafe
+	for _, cafe := range cafes {
+		if cafe.IsOpenAt(t) {
+			results = append(results, cafe)
+		}
+	}
+	return results
+}
+
+// isJapaneseHoliday は date が国民の祝日・振替休日・国民の休日かどうかを返す。
+// 春分・秋分の日は1980〜2099年の近似式で求め、2020・2021年の移動特例は扱わない。
+func isJapaneseHoliday(date time.Time) bool {
+	y, m, d := date.Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, jst)
+	if isNationalHoliday(day) {
+		return true
+	}
+
+	// 振替休日: 日曜の祝日から続く連休の翌平日
+	for prev := day.AddDate(0, 0, -1); isNationalHoliday(prev); prev = prev.AddDate(0, 0, -1) {
+		if prev.Weekday() == time.Sunday {
+			return true
+		}
+	}
+
+	// 国民の休日: 祝日に挟まれた平日
+	return day.Weekday() != time.Sunday &&
+		isNationalHoliday(day.AddDate(0, 0, -1)) && isNationalHoliday(day.AddDate(0, 0, 1))
+}
+
+func isNationalHoliday(day time.Time) bool {
+	y, m, d := day.Date()
+	switch {
+	case m == time.January && d == 1,
+		m == time.February && d == 11,
+		m == time.February && d == 23 && y >= 2020,
+		m == time.April && d == 29,
+		m == time.May && (d == 3 || d == 4 || d == 5),
+		m == time.August && d == 11 && y >= 2016,
+		m == time.November && (d == 3 || d == 23):
+		return true
+	case m == time.March:
+		return d == equinoxDay(y, 20.8431)
+	case m == time.September:
+		if d == equinoxDay(y, 23.2488) {
+			return true
+		}
+	}
+
+	// ハッピーマンデー: 成人の日・海の日・敬老の日・スポーツの日
+	if day.Weekday() == time.Monday {
+		nth := (d-1)/7 + 1
+		switch {
+		case m == time.January && nth == 2,
+			m == time.July && nth == 3,
+			m == time.September && nth == 3,
+			m == time.October && nth == 2:
+			return true
+		}
+	}
+	return false
+}
+
+func equinoxDay(year int, base float64) int {
+	n := year - 1980
+	return int(base + 0.242194*float64(n) - float64(n/4))
+}
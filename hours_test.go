@@ -0,0 +1,151 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func jstTime(year int, month time.Month, day, hour, min int) time.Time {
+	return time.Date(year, month, day, hour, min, 0, 0, jst)
+}
+
+func TestParseOpeningHours_IsOpenAt(t *testing.T) {
+	tests := []struct {
+		name     string
+		hours    string
+		at       time.Time
+		expected bool
+	}{
+		{"24時間営業 深夜", "24時間営業", jstTime(2026, 10, 20, 3, 0), true},
+		{"翌表記 日付またぎ", "10:00～翌5:00", jstTime(2026, 10, 20, 3, 0), true},
+		{"翌表記 閉店後", "10:00～翌5:00", jstTime(2026, 10, 20, 6, 0), false},
+		{"翌表記 開店後", "10:00～翌5:00", jstTime(2026, 10, 20, 23, 0), true},
+		{"29時表記", "10:00-29:00", jstTime(2026, 10, 20, 4, 59), true},
+		{"29時表記 閉店", "10:00-29:00", jstTime(2026, 10, 20, 5, 0), false},
+		{"全角数字", "１０：００〜２２：００", jstTime(2026, 10, 20, 21, 0), true},
+		{"時分表記", "10時～22時30分", jstTime(2026, 10, 20, 22, 15), true},
+		{"平日 開店前", "月～金 10:00-22:00 / 土日祝 9:00-23:00", jstTime(2026, 10, 19, 9, 30), false},
+		{"土曜 開店後", "月～金 10:00-22:00 / 土日祝 9:00-23:00", jstTime(2026, 10, 24, 9, 30), true},
+		{"祝日の月曜", "月～金 10:00-22:00 / 土日祝 9:00-23:00", jstTime(2026, 1, 12, 9, 30), true},
+		{"定休日", "10:00-22:00（水曜定休）", jstTime(2026, 10, 21, 12, 0), false},
+		{"定休日の翌日", "10:00-22:00（水曜定休）", jstTime(2026, 10, 22, 12, 0), true},
+		{"日付指定の休業", "24時間営業 12/31 休業", jstTime(2026, 12, 31, 12, 0), false},
+		{"休業日の翌日", "24時間営業 12/31 休業", jstTime(2027, 1, 1, 0, 30), true},
+		{"年末年始休業", "10:00-22:00 年末年始休業", jstTime(2027, 1, 2, 12, 0), false},
+		{"中休み", "11:00-14:00, 17:00-23:00", jstTime(2026, 10, 20, 15, 0), false},
+		{"中休み 夜", "11:00-14:00, 17:00-23:00", jstTime(2026, 10, 20, 18, 0), true},
+		{"UTCで指定", "10:00～翌5:00", time.Date(2026, 10, 19, 18, 0, 0, 0, time.UTC), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := ParseOpeningHours(tt.hours)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := h.IsOpenAt(tt.at); got != tt.expected {
+				t.Errorf("IsOpenAt(%v) for %q: expected %v, got %v", tt.at, tt.hours, tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseOpeningHours_Structure(t *testing.T) {
+	h, err := ParseOpeningHours("月～金 10:00-22:00 / 土日祝 9:00-翌1:00")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if h.Open24h {
+		t.Error("expected Open24h to be false")
+	}
+	if got := h.Weekly[time.Monday]; len(got) != 1 || got[0] != (TimeRange{600, 1320}) {
+		t.Errorf("unexpected Monday ranges: %v", got)
+	}
+	if got := h.Weekly[time.Sunday]; len(got) != 1 || got[0] != (TimeRange{540, 1500}) {
+		t.Errorf("unexpected Sunday ranges: %v", got)
+	}
+	if !h.HasHoliday || len(h.Holiday) != 1 {
+		t.Errorf("expected holiday ranges, got %v", h.Holiday)
+	}
+}
+
+func TestParseOpeningHours_Open24h(t *testing.T) {
+	tests := []struct {
+		hours    string
+		expected bool
+	}{
+		{"24時間営業", true},
+		{"0:00-24:00", true},
+		{"24時間営業 12/31 休業", true},
+		{"平日10:00~22:00、土日 24時間", false},
+		{"24時間営業（水曜定休）", false},
+		{"10:00～翌5:00", false},
+	}
+	for _, tt := range tests {
+		h, err := ParseOpeningHours(tt.hours)
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %v", tt.hours, err)
+		}
+		if h.Open24h != tt.expected {
+			t.Errorf("Open24h for %q: expected %v, got %v", tt.hours, tt.expected, h.Open24h)
+		}
+	}
+
+	// 土日だけ24時間営業なら、平日は指定の時間帯で土日は終日
+	h, _ := ParseOpeningHours("平日10:00~22:00、土日 24時間")
+	if got := h.Weekly[time.Monday]; len(got) != 1 || got[0] != (TimeRange{600, 1320}) {
+		t.Errorf("unexpected Monday ranges: %v", got)
+	}
+	if got := h.Weekly[time.Saturday]; len(got) != 1 || got[0] != (TimeRange{0, minutesPerDay}) {
+		t.Errorf("unexpected Saturday ranges: %v", got)
+	}
+}
+
+func TestParseOpeningHours_Unrecognized(t *testing.T) {
+	for _, text := range []string{"", "要問い合わせ"} {
+		if _, err := ParseOpeningHours(text); err == nil {
+			t.Errorf("expected error for %q", text)
+		}
+	}
+}
+
+func TestIsJapaneseHoliday(t *testing.T) {
+	tests := []struct {
+		date     time.Time
+		expected bool
+	}{
+		{jstTime(2026, 1, 1, 0, 0), true},    // 元日
+		{jstTime(2026, 1, 12, 0, 0), true},   // 成人の日
+		{jstTime(2026, 3, 20, 0, 0), true},   // 春分の日
+		{jstTime(2026, 5, 6, 0, 0), true},    // 振替休日
+		{jstTime(2026, 9, 22, 0, 0), true},   // 国民の休日
+		{jstTime(2026, 9, 23, 0, 0), true},   // 秋分の日
+		{jstTime(2026, 10, 16, 0, 0), false}, // 平日
+		{jstTime(2026, 10, 18, 0, 0), false}, // 日曜
+	}
+
+	for _, tt := range tests {
+		if got := isJapaneseHoliday(tt.date); got != tt.expected {
+			t.Errorf("isJapaneseHoliday(%s): expected %v, got %v", tt.date.Format("2006-01-02"), tt.expected, got)
+		}
+	}
+}
+
+func TestFilterOpenAt(t *testing.T) {
+	cafes := []NetCafe{
+		{Name: "24時間店", Hours: "24時間営業"},
+		{Name: "昼営業店", Hours: "10:00-22:00"},
+		{Name: "不明店", Hours: "要問い合わせ"},
+	}
+
+	results := filterOpenAt(cafes, jstTime(2026, 10, 20, 3, 0))
+	if len(results) != 1 || results[0].Name != "24時間店" {
+		t.Errorf("unexpected results at 3am: %+v", results)
+	}
+
+	results = filterOpenAt(cafes, jstTime(2026, 10, 20, 12, 0))
+	if len(results) != 2 {
+		t.Errorf("expected 2 results at noon, got %d", len(results))
+	}
+}
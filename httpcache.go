@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CachingTransport は GET のレスポンスをディスクに保存する RoundTripper。
+// Cache-Control の max-age・Expires の期間内は保存した本文を返し、期限切れなら
+// ETag・Last-Modified を使った条件付きリクエストで再検証する。リクエストの Cache-Control: no-cache か
+// withRevalidate の context があれば、期間内でも再検証する。
+type CachingTransport struct {
+	dir  string
+	next http.RoundTripper
+	now  func() time.Time
+}
+
+// NewCachingTransport は dir にレスポンスを保存し、キャッシュにないリクエストを next に渡す。
+// next が nil なら http.DefaultTransport を使う。
+func NewCachingTransport(dir string, next http.RoundTripper) *CachingTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &CachingTransport{dir: dir, next: next, now: time.Now}
+}
+
+type httpCacheEntry struct {
+	URL        string      `json:"url"`
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+	StoredAt   time.Time   `json:"stored_at"`
+}
+
+// httpCacheHeader はキャッシュから返したレスポンスに付けるヘッダー。値は "hit" か "revalidated"。
+const httpCacheHeader = "X-Netcafe-Cache"
+
+func (t *CachingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqCC := parseCacheControl(req.Header)
+	if req.Method != http.MethodGet || req.Header.Get("Range") != "" || hasDirective(reqCC, "no-store") {
+		return t.next.RoundTrip(req)
+	}
+
+	entry, err := t.load(req.URL.String())
+	if err != nil {
+		entry = nil
+	}
+	if entry != nil && !hasDirective(reqCC, "no-cache") && !revalidateFrom(req.Context()) && entry.fresh(t.now()) {
+		return entry.response(req, "hit"), nil
+	}
+
+	outReq := req
+	if entry != nil {
+		outReq = req.Clone(req.Context())
+		if etag := entry.Header.Get("ETag"); etag != "" {
+			outReq.Header.Set("If-None-Match", etag)
+		}
+		if lm := entry.Header.Get("Last-Modified"); lm != "" {
+			outReq.Header.Set("If-Modified-Since", lm)
+		}
+	}
+
+	resp, err := t.next.RoundTrip(outReq)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && entry != nil {
+		resp.Body.Close()
+		for k, v := range resp.Header {
+			if k != "Content-Length" {
+				entry.Header[k] = v
+			}
+		}
+		entry.StoredAt = t.now()
+		t.save(entry)
+		return entry.response(req, "revalidated"), nil
+	}
+
+	if resp.StatusCode != http.StatusOK || hasDirective(parseCacheControl(resp.Header), "no-store") {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	t.save(&httpCacheEntry{
+		URL:        req.URL.String(),
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header.Clone(),
+		Body:       body,
+		StoredAt:   t.now(),
+	})
+	return resp, nil
+}
+
+type revalidateKey struct{}
+
+// withRevalidate は ctx のリクエストで、CachingTransport に期間内のレスポンスも再検証させる。-refresh で使う。
+func withRevalidate(ctx context.Context) context.Context {
+	return context.WithValue(ctx, revalidateKey{}, true)
+}
+
+func revalidateFrom(ctx context.Context) bool {
+	v, _ := ctx.Value(revalidateKey{}).(bool)
+	return v
+}
+
+func (t *CachingTransport) path(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(t.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (t *CachingTransport) load(url string) (*httpCacheEntry, error) {
+	b, err := os.ReadFile(t.path(url))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entry httpCacheEntry
+	if err := json.Unmarshal(b, &entry); err != nil {
+		return nil, err
+	}
+	// ハッシュの衝突に備えて URL も確かめる
+	if entry.URL != url || entry.Header == nil {
+		return nil, nil
+	}
+	return &entry, nil
+}
+
+// save の失敗はキャッシュしないだけで取得自体は成功させる。
+func (t *CachingTransport) save(entry *httpCacheEntry) {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	writeFileAtomic(t.path(entry.URL), b)
+}
+
+// fresh は再検証せずに使ってよいかどうかを返す。max-age を Expires より優先する。
+func (e *httpCacheEntry) fresh(now time.Time) bool {
+	cc := parseCacheControl(e.Header)
+	if hasDirective(cc, "no-cache") {
+		return false
+	}
+	age := now.Sub(e.StoredAt)
+	if v, ok := cc["max-age"]; ok {
+		seconds, err := strconv.Atoi(v)
+		return err == nil && age < time.Duration(seconds)*time.Second
+	}
+	if v := e.Header.Get("Expires"); v != "" {
+		expires, err := http.ParseTime(v)
+		if err != nil {
+			return false
+		}
+		date, err := http.ParseTime(e.Header.Get("Date"))
+		if err != nil {
+			date = e.StoredAt
+		}
+		return age < expires.Sub(date)
+	}
+	return false
+}
+
+func (e *httpCacheEntry) response(req *http.Request, status string) *http.Response {
+	header := e.Header.Clone()
+	header.Set(httpCacheHeader, status)
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode)),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// parseCacheControl は Cache-Control ヘッダーをディレクティブ名（小文字）から値への対応にする。
+func parseCacheControl(h http.Header) map[string]string {
+	cc := make(map[string]string)
+	for _, v := range h.Values("Cache-Control") {
+		for _, part := range strings.Split(v, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+			if name == "" {
+				continue
+			}
+			cc[strings.ToLower(name)] = strings.Trim(value, `"`)
+		}
+	}
+	return cc
+}
+
+func hasDirective(cc map[string]string, name string) bool {
+	_, ok := cc[name]
+	return ok
+}
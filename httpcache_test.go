@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestCachingTransport(t *testing.T) (*CachingTransport, *time.Time) {
+	t.Helper()
+	clock := time.Date(2026, 10, 20, 12, 0, 0, 0, time.UTC)
+	transport := NewCachingTransport(t.TempDir(), nil)
+	transport.now = func() time.Time { return clock }
+	return transport, &clock
+}
+
+func get(t *testing.T, client *http.Client, url string) (*http.Response, string) {
+	t.Helper()
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return resp, string(body)
+}
+
+func TestCachingTransport_ETag(t *testing.T) {
+	var requests, notModified int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte("shop list"))
+	}))
+	defer server.Close()
+
+	transport, _ := newTestCachingTransport(t)
+	client := &http.Client{Transport: transport}
+
+	resp, body := get(t, client, server.URL)
+	if body != "shop list" || resp.Header.Get(httpCacheHeader) != "" {
+		t.Fatalf("unexpected first response: %q %v", body, resp.Header)
+	}
+
+	resp, body = get(t, client, server.URL)
+	if requests != 2 || notModified != 1 {
+		t.Errorf("expected conditional request, requests=%d notModified=%d", requests, notModified)
+	}
+	if body != "shop list" || resp.StatusCode != http.StatusOK {
+		t.Errorf("expected cached body with 200, got %d %q", resp.StatusCode, body)
+	}
+	if got := resp.Header.Get(httpCacheHeader); got != "revalidated" {
+		t.Errorf("expected revalidated response, got %q", got)
+	}
+}
+
+func TestCachingTransport_LastModified(t *testing.T) {
+	lastModified := "Tue, 20 Oct 2026 03:00:00 GMT"
+	var ifModifiedSince string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ifModifiedSince = r.Header.Get("If-Modified-Since")
+		w.Header().Set("Last-Modified", lastModified)
+		if ifModifiedSince == lastModified {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte("shop list"))
+	}))
+	defer server.Close()
+
+	transport, _ := newTestCachingTransport(t)
+	client := &http.Client{Transport: transport}
+
+	get(t, client, server.URL)
+	_, body := get(t, client, server.URL)
+	if ifModifiedSince != lastModified {
+		t.Errorf("expected If-Modified-Since %q, got %q", lastModified, ifModifiedSince)
+	}
+	if body != "shop list" {
+		t.Errorf("expected cached body, got %q", body)
+	}
+}
+
+func TestCachingTransport_MaxAge(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Cache-Control", "public, max-age=600")
+		w.Write([]byte("shop list"))
+	}))
+	defer server.Close()
+
+	transport, clock := newTestCachingTransport(t)
+	client := &http.Client{Transport: transport}
+
+	get(t, client, server.URL)
+	*clock = clock.Add(5 * time.Minute)
+	resp, body := get(t, client, server.URL)
+	if requests != 1 || body != "shop list" || resp.Header.Get(httpCacheHeader) != "hit" {
+		t.Errorf("expected fresh cache hit, requests=%d body=%q", requests, body)
+	}
+
+	*clock = clock.Add(10 * time.Minute)
+	get(t, client, server.URL)
+	if requests != 2 {
+		t.Errorf("expected stale entry to be fetched again, requests=%d", requests)
+	}
+
+	// 再検証を求められたら期間内でも取得し直す
+	req, _ := http.NewRequestWithContext(withRevalidate(context.Background()), http.MethodGet, server.URL, nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if requests != 3 || resp.Header.Get(httpCacheHeader) == "hit" {
+		t.Errorf("expected fresh entry to be revalidated, requests=%d cache=%q", requests, resp.Header.Get(httpCacheHeader))
+	}
+}
+
+func TestCachingTransport_Expires(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Date", "Tue, 20 Oct 2026 12:00:00 GMT")
+		w.Header().Set("Expires", "Tue, 20 Oct 2026 13:00:00 GMT")
+		w.Write([]byte("shop list"))
+	}))
+	defer server.Close()
+
+	transport, clock := newTestCachingTransport(t)
+	client := &http.Client{Transport: transport}
+
+	get(t, client, server.URL)
+	*clock = clock.Add(30 * time.Minute)
+	get(t, client, server.URL)
+	if requests != 1 {
+		t.Errorf("expected cache hit before Expires, requests=%d", requests)
+	}
+	*clock = clock.Add(time.Hour)
+	get(t, client, server.URL)
+	if requests != 2 {
+		t.Errorf("expected fetch after Expires, requests=%d", requests)
+	}
+}
+
+func TestCachingTransport_NotStored(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		cc     string
+		method string
+	}{
+		{name: "no-store", status: http.StatusOK, cc: "no-store", method: http.MethodGet},
+		{name: "error status", status: http.StatusServiceUnavailable, cc: "max-age=600", method: http.MethodGet},
+		{name: "POST", status: http.StatusOK, cc: "max-age=600", method: http.MethodPost},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				w.Header().Set("Cache-Control", tt.cc)
+				w.Header().Set("ETag", `"v1"`)
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			transport, _ := newTestCachingTransport(t)
+			client := &http.Client{Transport: transport}
+			for i := 0; i < 2; i++ {
+				req, _ := http.NewRequest(tt.method, server.URL, nil)
+				resp, err := client.Do(req)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				resp.Body.Close()
+				if resp.StatusCode != tt.status {
+					t.Errorf("expected status %d, got %d", tt.status, resp.StatusCode)
+				}
+			}
+			if requests != 2 {
+				t.Errorf("expected response not to be cached, requests=%d", requests)
+			}
+		})
+	}
+}
+
+func TestCachingTransport_Scraper(t *testing.T) {
+	htmlContent := `
+	<html><body>
+		<div class="shop-item">
+			<h3 class="shop-name">池袋西口ROSA店</h3>
+			<div class="shop-address">東京都豊島区西池袋1-37-12</div>
+		</div>
+	</body></html>
+	`
+	var notModified int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"list-1"`)
+		if r.Header.Get("If-None-Match") == `"list-1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(htmlContent))
+	}))
+	defer server.Close()
+
+	transport, _ := newTestCachingTransport(t)
+	scraper := NewScraper(WithBaseURL("jiqoo", server.URL), WithTransport(transport))
+	for i := 0; i < 2; i++ {
+		cafes, err := builtinSource(t, "jiqoo").Scrape(context.Background(), scraper)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(cafes) != 1 || cafes[0].Name != "池袋西口ROSA店" {
+			t.Errorf("unexpected stores: %+v", cafes)
+		}
+	}
+	if notModified != 1 {
+		t.Errorf("expected second scrape to be revalidated, got %d", notModified)
+	}
+}
+
+func TestParseCacheControl(t *testing.T) {
+	h := http.Header{}
+	h.Add("Cache-Control", `Public, max-age="300"`)
+	h.Add("Cache-Control", "no-cache")
+
+	cc := parseCacheControl(h)
+	if cc["max-age"] != "300" || !hasDirective(cc, "public") || !hasDirective(cc, "no-cache") {
+		t.Errorf("unexpected directives: %v", cc)
+	}
+}
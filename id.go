@@ -0,0 +1,117 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var (
+	shopCodePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	// shopCodeParams は店舗コードを表すことの多いクエリパラメーター。
+	shopCodeParams = []string{"shop_id", "store_id", "shop", "store", "id", "code"}
+	// nonCodeSegments は一覧ページや詳細ページのスクリプトを表すパスの要素。店舗コードとはみなさない。
+	nonCodeSegments = map[string]bool{
+		"": true, "shop": true, "shops": true, "store": true, "stores": true, "index": true, "list": true,
+		"detail": true, "details": true, "shop_detail": true, "store_detail": true, "show": true, "view": true, "info": true,
+	}
+)
+
+// storeID は店舗を実行をまたいで識別する ID を返す。
+// 詳細ページの URL に店舗コード（/shop/shinjuku/ の shinjuku など）があれば「チェーンID-店舗コード」、
+// なければ「チェーンID-住所と電話番号のハッシュ」になる。
+func (c NetCafe) storeID() string {
+	chain := c.chainSlug()
+	if code := shopCode(c.URL); code != "" {
+		return chain + "-" + code
+	}
+	return chain + "-" + storeHash(c)
+}
+
+// chainSlug は店舗のチェーンID。チェーンも取得元の記録もなければ URL のホストが一致する取得元から求める。
+func (c NetCafe) chainSlug() string {
+	if c.Chain.ID != "" {
+		return c.Chain.ID
+	}
+	for _, src := range c.Sources {
+		if src != sampleSource {
+			return src
+		}
+	}
+	if u, err := url.Parse(c.URL); err == nil && u.Host != "" {
+		host := strings.TrimPrefix(u.Hostname(), "www.")
+		for _, src := range RegisteredSources() {
+			if b, err := url.Parse(src.BaseURL()); err == nil && strings.TrimPrefix(b.Hostname(), "www.") == host {
+				return src.ChainID()
+			}
+		}
+	}
+	return "store"
+}
+
+// shopCode は詳細ページの URL から店舗コードを取り出す。
+// shop_id などのクエリパラメーターがあればその値、なければパスの最後の要素（拡張子を除く）を使う。
+// detail.php?shop_id=1 のように同じスクリプトを全店舗で共有するサイトでも店舗ごとに異なるコードになる。
+func shopCode(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	q := u.Query()
+	for _, key := range shopCodeParams {
+		if v := q.Get(key); v != "" {
+			if shopCodePattern.MatchString(v) {
+				return v
+			}
+			return ""
+		}
+	}
+	code := strings.TrimSuffix(path.Base(u.Path), path.Ext(u.Path))
+	if code == "/" || code == "." || nonCodeSegments[strings.ToLower(code)] || isPrefectureSlug(code) {
+		return ""
+	}
+	if !shopCodePattern.MatchString(code) {
+		return ""
+	}
+	return code
+}
+
+func isPrefectureSlug(s string) bool {
+	for _, p := range prefectures {
+		if strings.EqualFold(s, p.Slug) {
+			return true
+		}
+	}
+	return false
+}
+
+// storeHash は正規化した住所と電話番号（どちらもなければ店名）から作る12桁のハッシュ。
+func storeHash(c NetCafe) string {
+	addr := addressKey(c)
+	if addr == "" {
+		addr = strings.Join(strings.Fields(normalizeWidth(c.Location)), "")
+	}
+	phone := phoneKey(c)
+	if phone == "" {
+		phone = strings.Join(strings.Fields(c.Phone), "")
+	}
+	key := addr + "|" + phone
+	if key == "|" {
+		key = c.Name
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// GetByID は ID が id の店舗を返す。
+func (s *NetCafeService) GetByID(id string) (NetCafe, bool) {
+	for _, cafe := range s.GetAll() {
+		if cafe.ID == id {
+			return cafe, true
+		}
+	}
+	return NetCafe{}, false
+}
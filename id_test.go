@@ -0,0 +1,86 @@
+package main
+
+import (
+	"regexp"
+	"testing"
+)
+
+// storeIDPattern は NetCafe.ID の書式（チェーンID-店舗コード）。
+var storeIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*-[A-Za-z0-9_-]+$`)
+
+func TestShopCode(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{"https://www.kaikatsu.jp/shop/shinjuku/", "shinjuku"},
+		{"https://www.cybac.com/shop/tokyo/akiba.html", "akiba"},
+		{"https://jiqoo.jp/shop/detail.php?shop_id=1234", "1234"},
+		{"https://www.example.com/tenpo/?id=5", "5"},
+		{"https://www.example.com/shop/shop_detail.php", ""},
+		{"https://jiqoo.jp/shop/?shop_id=1234", "1234"},
+		{"https://www.customa.jp/shop/index.html?id=A-01", "A-01"},
+		{"https://www.kaikatsu.jp/shop/tokyo/", ""},
+		{"https://www.aprecio.co.jp/shop/?pref=13", ""},
+		{"https://www.kaikatsu.jp/", ""},
+		{"https://www.geragera.co.jp/shop/新宿/", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := shopCode(tt.url); got != tt.expected {
+			t.Errorf("shopCode(%q) = %q, expected %q", tt.url, got, tt.expected)
+		}
+	}
+}
+
+func TestNetCafe_StoreID(t *testing.T) {
+	detail := normalized(NetCafe{Name: "新宿西口店", URL: "https://www.kaikatsu.jp/shop/shinjuku/"}, "kaikatsu")
+	if detail.ID != "kaikatsu-shinjuku" {
+		t.Errorf("expected shop code from URL, got %q", detail.ID)
+	}
+
+	// 店舗コードがなければ住所と電話番号のハッシュ。表記ゆれがあっても同じ ID になる
+	a := normalized(NetCafe{Name: "新宿店", Location: "東京都新宿区新宿3-1-1", Phone: "03-1234-5678", URL: "https://jiqoo.jp/"}, "jiqoo")
+	b := normalized(NetCafe{Name: "新宿店", Location: "東京都新宿区新宿三丁目1番1号 ２F", Phone: "0312345678"}, "jiqoo")
+	if a.ID != b.ID || !storeIDPattern.MatchString(a.ID) || a.ID[:6] != "jiqoo-" {
+		t.Errorf("expected the same hashed ID, got %q and %q", a.ID, b.ID)
+	}
+	c := normalized(NetCafe{Name: "新宿店", Location: "東京都新宿区新宿3-1-2", Phone: "03-1234-5678"}, "jiqoo")
+	if c.ID == a.ID {
+		t.Errorf("different stores should have different IDs: %q", c.ID)
+	}
+
+	// 詳細ページのスクリプトを共有するサイトでも店舗ごとに ID が分かれる
+	shop1 := normalized(NetCafe{Name: "新宿店", URL: "https://jiqoo.jp/shop/detail.php?shop_id=1"}, "jiqoo")
+	shop2 := normalized(NetCafe{Name: "池袋店", URL: "https://jiqoo.jp/shop/detail.php?shop_id=2"}, "jiqoo")
+	if shop1.ID != "jiqoo-1" || shop2.ID != "jiqoo-2" {
+		t.Errorf("expected distinct IDs from shop_id, got %q and %q", shop1.ID, shop2.ID)
+	}
+
+	// サンプルデータは URL のホストからチェーンを求める
+	sample := normalized(NetCafe{Name: "マンボー 渋谷宮益坂店", URL: "https://manboo.co.jp/"}, sampleSource)
+	if sample.ID[:7] != "manboo-" {
+		t.Errorf("expected chain from URL host, got %q", sample.ID)
+	}
+}
+
+func TestNetCafeService_GetByID(t *testing.T) {
+	service := NewNetCafeService()
+	stores := service.GetAll()
+
+	seen := make(map[string]bool)
+	for _, s := range stores {
+		if s.ID == "" || seen[s.ID] {
+			t.Errorf("expected a unique ID, got %q", s.ID)
+		}
+		seen[s.ID] = true
+	}
+
+	cafe, ok := service.GetByID(stores[3].ID)
+	if !ok || cafe.Name != stores[3].Name {
+		t.Errorf("GetByID(%q) = %+v, %v", stores[3].ID, cafe, ok)
+	}
+	if _, ok := service.GetByID("kaikatsu-unknown"); ok {
+		t.Error("expected unknown ID not to be found")
+	}
+}
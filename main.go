@@ -1,94 +1,157 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 )
 
 type NetCafe struct {
-	Name     string `json:"name"`
-	Location string `json:"location"`
-	Hours    string `json:"hours"`
-	Phone    string `json:"phone"`
-	URL      string `json:"url"`
+	Name     string   `json:"name"`
+	Location string   `json:"location"`
+	Address  *Address `json:"address,omitempty"`
+	Hours    string   `json:"hours"`
+	Phone    string   `json:"phone"`
+	// PhoneE164 は Phone が日本の電話番号として有効なときだけ埋まる。
+	PhoneE164 string `json:"phone_e164,omitempty"`
+	URL       string `json:"url"`
+	// ID は実行をまたいで同じ店舗を指す識別子（チェーンID-店舗コード）。
+	ID string `json:"id,omitempty"`
+	// Chain は店舗のチェーン。Name にはチェーン名を含めない（表示用の店名は FullName）。
+	Chain Chain `json:"chain"`
+	// Geo はページに構造化データ（schema.org の geo）があるときだけ埋まる。
+	Geo *GeoPoint `json:"geo,omitempty"`
+	// Amenities と Prices は詳細ページを取得したとき（-details）だけ埋まる。
+	Amenities []string `json:"amenities,omitempty"`
+	Prices    []Price  `json:"prices,omitempty"`
+	// Sources は店舗の情報を提供した取得元の ChainID（サンプルデータは "sample"）。
+	Sources []string `json:"sources,omitempty"`
+}
+
+// normalize は取得した生テキストから構造化したフィールドを埋める。
+func (c *NetCafe) normalize() {
+	if c.Location != "" {
+		c.Address = ParseAddress(c.Location)
+	}
+	if p, err := ParsePhone(c.Phone); err == nil {
+		c.Phone = p.National()
+		c.PhoneE164 = p.E164()
+	}
+	c.ID = c.storeID()
 }
 
 type NetCafeService struct {
 	client *http.Client
+
+	mu     sync.RWMutex
 	stores []NetCafe
 }
 
 func NewNetCafeService() *NetCafeService {
+	return newNetCafeService(getSampleStores())
+}
+
+func newNetCafeService(stores []NetCafe) *NetCafeService {
 	return &NetCafeService{
 		client: &http.Client{
-			Timeout: 10 * time.Second,
+			Transport: NewRetryTransport(NewTimeoutTransport(nil, 10*time.Second), defaultRetryPolicy),
 		},
-		stores: getSampleStores(),
+		stores: stores,
 	}
 }
 
 func getSampleStores() []NetCafe {
-	return []NetCafe{
+	stores := []NetCafe{
 		{
-			Name:     "快活CLUB 新宿西口店",
+			Name:     "新宿西口店",
+			Chain:    Chain{ID: "kaikatsu", Name: "快活CLUB"},
 			Location: "東京都新宿区西新宿1-12-9",
 			Hours:    "24時間営業",
 			Phone:    "03-5321-6166",
 			URL:      "https://www.kaikatsu.jp/",
 		},
 		{
-			Name:     "自遊空間 池袋西口ROSA店",
+			Name:     "池袋西口ROSA店",
+			Chain:    Chain{ID: "jiqoo", Name: "自遊空間"},
 			Location: "東京都豊島区西池袋1-37-12",
 			Hours:    "24時間営業",
 			Phone:    "03-5391-7778",
 			URL:      "https://jiqoo.jp/",
 		},
 		{
-			Name:     "DiCE 秋葉原店",
+			Name:     "秋葉原店",
+			Chain:    Chain{ID: "dice", Name: "DiCE"},
 			Location: "東京都千代田区外神田1-11-5",
 			Hours:    "24時間営業",
 			Phone:    "03-5298-1281",
 			URL:      "https://www.diskcity.co.jp/",
 		},
 		{
-			Name:     "マンボー 渋谷宮益坂店",
+			Name:     "渋谷宮益坂店",
+			Chain:    Chain{ID: "manboo", Name: "マンボー"},
 			Location: "東京都渋谷区渋谷1-12-1",
 			Hours:    "24時間営業",
 			Phone:    "03-5766-6010",
 			URL:      "https://manboo.co.jp/",
 		},
 		{
-			Name:     "アプレシオ 新宿歌舞伎町店",
+			Name:     "新宿歌舞伎町店",
+			Chain:    Chain{ID: "aprecio", Name: "アプレシオ"},
 			Location: "東京都新宿区歌舞伎町1-20-1",
 			Hours:    "24時間営業",
 			Phone:    "03-5155-4486",
 			URL:      "https://www.aprecio.co.jp/",
 		},
 	}
+	for i := range stores {
+		stores[i].Sources = []string{sampleSource}
+		stores[i].normalize()
+	}
+	return stores
 }
 
 func (s *NetCafeService) SearchByName(keyword string) []NetCafe {
 	var results []NetCafe
+	phoneDigits, isPhone := phoneQueryDigits(keyword)
 	keyword = strings.ToLower(keyword)
 	
-	for _, cafe := range s.stores {
-		if strings.Contains(strings.ToLower(cafe.Name), keyword) ||
-			strings.Contains(strings.ToLower(cafe.Location), keyword) {
+	for _, cafe := range s.GetAll() {
+		if strings.Contains(strings.ToLower(cafe.FullName()), keyword) ||
+			strings.Contains(strings.ToLower(cafe.Location), keyword) ||
+			(isPhone && cafe.matchesPhone(phoneDigits)) {
 			results = append(results, cafe)
 		}
 	}
 	return results
 }
 
+// GetAll は現在の店舗一覧を返す。返したスライスは SetStores で差し替えられても変更されない。
 func (s *NetCafeService) GetAll() []NetCafe {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	return s.stores
 }
 
+// SetStores は店舗一覧を丸ごと差し替える。読み出し中の GetAll・SearchByName と並行して呼んでよい。
+// 渡したスライスは以後変更しないこと。
+func (s *NetCafeService) SetStores(stores []NetCafe) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stores = stores
+}
+
 func (s *NetCafeService) FetchFromAPI(url string) (*NetCafe, error) {
 	resp, err := s.client.Get(url)
 	if err != nil {
@@ -109,90 +172,436 @@ func (s *NetCafeService) FetchFromAPI(url string) (*NetCafe, error) {
 	return &cafe, nil
 }
 
-func printCafe(cafe NetCafe) {
-	fmt.Println(strings.Repeat("=", 50))
-	fmt.Printf("店舗名: %s\n", cafe.Name)
-	fmt.Printf("場所:   %s\n", cafe.Location)
-	fmt.Printf("営業時間: %s\n", cafe.Hours)
-	fmt.Printf("電話番号: %s\n", cafe.Phone)
-	fmt.Printf("URL:    %s\n", cafe.URL)
+func printCafe(w io.Writer, cafe NetCafe) {
+	fmt.Fprintln(w, strings.Repeat("=", 50))
+	fmt.Fprintf(w, "店舗名: %s\n", cafe.FullName())
+	fmt.Fprintf(w, "場所:   %s\n", cafe.Location)
+	fmt.Fprintf(w, "営業時間: %s\n", cafe.Hours)
+	fmt.Fprintf(w, "電話番号: %s\n", cafe.Phone)
+	fmt.Fprintf(w, "URL:    %s\n", cafe.URL)
+	if cafe.ID != "" {
+		fmt.Fprintf(w, "ID:     %s\n", cafe.ID)
+	}
+	if cafe.Geo != nil {
+		fmt.Fprintf(w, "緯度経度: %s\n", cafe.Geo)
+	}
+	if len(cafe.Amenities) > 0 {
+		fmt.Fprintf(w, "設備:   %s\n", strings.Join(cafe.Amenities, "、"))
+	}
+	for _, p := range cafe.Prices {
+		fmt.Fprintf(w, "料金:   %s\n", p)
+	}
+}
+
+// baseURLFlag は -base-url chain=URL を繰り返し受け付ける。
+type baseURLFlag []ScraperOption
+
+func (f *baseURLFlag) String() string { return "" }
+
+func (f *baseURLFlag) Set(value string) error {
+	chainID, baseURL, ok := strings.Cut(value, "=")
+	if !ok || chainID == "" || baseURL == "" {
+		return fmt.Errorf("expected chain=URL, got %q", value)
+	}
+	*f = append(*f, WithBaseURL(chainID, baseURL))
+	return nil
+}
+
+// loadSourcesFlag は -sources の指定があれば取得元の定義を読み込む。
+func loadSourcesFlag(path string) ([]SourceSpec, error) {
+	if path == "" {
+		return nil, nil
+	}
+	return LoadSourceSpecs(path)
+}
+
+// scraperFlags は通常の実行と serve で共通の、取得に関するフラグ。
+type scraperFlags struct {
+	concurrency *int
+	userAgent   *string
+	rate        *float64
+	attempts    *int
+	details     *int
+	maxPages    *int
+	pref        *string
+	sources     *string
+	baseURLs    baseURLFlag
+}
+
+// registerScraperFlags は取得に関するフラグを fs に登録する。
+func registerScraperFlags(fs *flag.FlagSet) *scraperFlags {
+	f := &scraperFlags{
+		concurrency: fs.Int("concurrency", defaultConcurrency, "同時に取得する取得元の数"),
+		userAgent:   fs.String("user-agent", defaultUserAgent, "取得時に送る User-Agent"),
+		rate:        fs.Float64("rate", defaultRateLimit, "ホストごとの1秒あたりのリクエスト数の上限"),
+		attempts:    fs.Int("max-attempts", defaultRetryPolicy.MaxAttempts, "一時的な失敗を再試行するときの試行回数の上限 (1で再試行しない)"),
+		details:     fs.Int("details", 0, "各店舗の詳細ページも取得し、n 件ずつ並行して解析 (0で取得しない)"),
+		maxPages:    fs.Int("max-pages", defaultMaxPages, "店舗一覧のページ送りをたどるページ数の上限"),
+		pref:        fs.String("pref", "", "取得する都道府県 (例: 13,大阪,kanagawa、all で全国、既定は東京都)"),
+		sources:     fs.String("sources", "", "取得元の定義を読み込むファイルまたはディレクトリ (YAML/JSON)"),
+	}
+	fs.Var(&f.baseURLs, "base-url", "取得元の接続先を差し替え (例: kaikatsu=http://localhost:8080)")
+	return f
+}
+
+// transport はフラグに従って newHTTPTransport で RoundTripper を作る。
+func (f *scraperFlags) transport(cacheDir string) http.RoundTripper {
+	return newHTTPTransport(cacheDir, *f.userAgent, *f.rate, *f.attempts)
+}
+
+// options は -pref と -sources を解析し、transport で取得する Scraper のオプションを返す。
+// エラーはそのまま利用者に表示できる。
+func (f *scraperFlags) options(transport http.RoundTripper) ([]ScraperOption, error) {
+	prefs, err := parsePrefectures(*f.pref)
+	if err != nil {
+		return nil, fmt.Errorf("-pref の指定が不正です: %w", err)
+	}
+	specs, err := loadSourcesFlag(*f.sources)
+	if err != nil {
+		return nil, fmt.Errorf("-sources の読み込みに失敗しました: %w", err)
+	}
+	opts := []ScraperOption{
+		WithConcurrency(*f.concurrency),
+		WithTransport(transport),
+		WithRateLimit(*f.rate),
+		WithDetails(*f.details),
+		WithMaxPages(*f.maxPages),
+		WithPrefectures(prefs...),
+		WithSourceSpecs(specs...),
+	}
+	return append(opts, f.baseURLs...), nil
+}
+
+// newHTTPTransport は取得元と API の取得で共有する RoundTripper を返す。
+// キャッシュで済まなかったリクエストだけが再試行の対象になり、レート制限と robots.txt の
+// 確認を試行ごとに経て送られる。時間の上限は試行ごとで、レート制限や再試行の待ち時間は含めない。
+// cacheDir が空ならレスポンスをキャッシュしない。
+func newHTTPTransport(cacheDir, userAgent string, rate float64, maxAttempts int) http.RoundTripper {
+	policy := defaultRetryPolicy
+	policy.MaxAttempts = maxAttempts
+	var rt http.RoundTripper = NewPoliteTransport(NewTimeoutTransport(http.DefaultTransport, defaultRequestTimeout), userAgent, rate)
+	rt = NewRetryTransport(rt, policy)
+	if cacheDir != "" {
+		rt = NewCachingTransport(filepath.Join(cacheDir, "http"), rt)
+	}
+	return rt
+}
+
+const (
+	exitOK            = 0
+	exitFailure       = 1
+	exitUsage         = 2
+	exitScrapePartial = 3
+)
+
+func printScrapeResult(w io.Writer, result *ScrapeResult) {
+	var failed, incomplete []SourceResult
+	for _, r := range result.Sources {
+		if !r.OK() {
+			failed = append(failed, r)
+			continue
+		}
+		if r.DetailErr != nil {
+			incomplete = append(incomplete, r)
+		}
+		if r.Cached {
+			fmt.Fprintf(w, "%s: %d店舗 (キャッシュ、%s に取得)\n", r.Source, r.Count, r.FetchedAt.Local().Format("2006-01-02 15:04"))
+			continue
+		}
+		notes := []string{r.Duration.Round(time.Millisecond).String()}
+		if r.Pages > 1 {
+			notes = append(notes, fmt.Sprintf("%dページ", r.Pages))
+		}
+		if r.Details > 0 {
+			notes = append(notes, fmt.Sprintf("詳細 %d 件", r.Details))
+		}
+		if r.Retries > 0 {
+			notes = append(notes, fmt.Sprintf("再試行 %d 回", r.Retries))
+		}
+		fmt.Fprintf(w, "%s: %d店舗を取得 (%s)\n", r.Source, r.Count, strings.Join(notes, "、"))
+	}
+
+	if len(failed) > 0 {
+		fmt.Fprintln(w, "\n取得に失敗したサイト:")
+		for _, r := range failed {
+			if r.Attempts > 1 {
+				fmt.Fprintf(w, "  - %s: %v (%d 回試行)\n", r.Source, r.Err, r.Attempts)
+			} else {
+				fmt.Fprintf(w, "  - %s: %v\n", r.Source, r.Err)
+			}
+			if r.Cached {
+				fmt.Fprintf(w, "    %s に取得したキャッシュ (%d店舗) を使用します\n", r.FetchedAt.Local().Format("2006-01-02 15:04"), r.Count)
+			}
+		}
+	}
+
+	if len(incomplete) > 0 {
+		fmt.Fprintln(w, "\n詳細ページの取得に一部失敗したサイト (一覧の情報を使用します):")
+		for _, r := range incomplete {
+			fmt.Fprintf(w, "  - %s: %v\n", r.Source, r.DetailErr)
+		}
+	}
+}
+
+// scrapeStores は scrape の結果を info に表示し、店舗一覧と終了コードを返す。
+// すべての取得元が失敗した場合は、キャッシュに残っている店舗があればそれを、なければサンプルデータを返す。
+func scrapeStores(ctx context.Context, info io.Writer, scrape func(context.Context) (*ScrapeResult, error)) ([]NetCafe, int) {
+	result, err := scrape(ctx)
+	printScrapeResult(info, result)
+
+	exitCode := exitOK
+	var partial *PartialScrapeError
+	if errors.As(err, &partial) && !partial.AllFailed() {
+		exitCode = exitScrapePartial
+		err = nil
+	}
+	if err != nil {
+		fmt.Fprintf(info, "エラー: %v\n", err)
+		if len(result.Stores) > 0 {
+			fmt.Fprintln(info, "前回取得したキャッシュを使用します。")
+			return result.Stores, exitFailure
+		}
+		fmt.Fprintln(info, "サンプルデータを使用します。")
+		return getSampleStores(), exitFailure
+	}
+	fmt.Fprintf(info, "\n合計 %d 店舗の情報を取得しました。\n", len(result.Stores))
+	return result.Stores, exitCode
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		os.Exit(runServe(os.Args[2:]))
+	}
+	os.Exit(run())
+}
+
+// runServe は netcafe serve サブコマンド。店舗情報を HTTP API で公開する。
+func runServe(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
 	var (
-		scrapeFlag = flag.Bool("scrape", false, "Webサイトから最新の店舗情報を取得")
-		helpFlag   = flag.Bool("help", false, "ヘルプを表示")
+		addrFlag    = fs.String("addr", ":8080", "待ち受けるアドレス")
+		scrapeFlag  = fs.Bool("scrape", false, "起動時にWebサイトから最新の店舗情報を取得")
+		refreshFlag = fs.Duration("refresh-interval", 0, "店舗情報を定期的に再取得する間隔 (例: 1h、0で無効)")
+		jitterFlag  = fs.Duration("refresh-jitter", 30*time.Second, "再取得の間隔に加えるランダムな遅延の上限")
+		scrapeFlags = registerScraperFlags(fs)
 	)
+	fs.Parse(args)
+
+	cacheDir, _ := defaultCacheDir()
+	transport := scrapeFlags.transport(cacheDir)
+	opts, err := scrapeFlags.options(transport)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	scraper := NewScraper(opts...)
+	service := newNetCafeService(getSampleStores())
+	service.client.Transport = transport
+	handler := NewServer(service)
+
+	if *refreshFlag > 0 {
+		handler.refresher = NewRefresher(service, scraper, *refreshFlag, *jitterFlag)
+		if *scrapeFlag {
+			if err := handler.refresher.Refresh(ctx); err != nil {
+				log.Printf("initial scrape failed: %v", err)
+			}
+		}
+		go handler.refresher.Run(ctx)
+	} else if *scrapeFlag {
+		stores, _ := scrapeStores(ctx, os.Stderr, scraper.ScrapeAll)
+		service.SetStores(stores)
+	}
+
+	srv := &http.Server{
+		Addr:              *addrFlag,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("listening on %s", *addrFlag)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("server error: %v", err)
+		return exitFailure
+	}
+	return exitOK
+}
+
+func run() int {
+	var (
+		scrapeFlag  = flag.Bool("scrape", false, "Webサイトから最新の店舗情報を取得")
+		refreshFlag = flag.Bool("refresh", false, "キャッシュを使わずにWebサイトから取得し直す (-scrape を含む)")
+		helpFlag    = flag.Bool("help", false, "ヘルプを表示")
+		timeoutFlag = flag.Duration("timeout", 0, "取得全体のタイムアウト (例: 30s、0で無制限)")
+		openNowFlag = flag.Bool("open-now", false, "現在営業中の店舗だけを表示")
+		wardFlag    = flag.String("ward", "", "市区町村・行政区で絞り込み (例: 新宿区)")
+		chainFlag   = flag.String("chain", "", "チェーンで絞り込み (例: kaikatsu,自遊空間)")
+		formatFlag  = flag.String("format", formatText, "出力形式 ("+strings.Join(outputFormats, ", ")+")")
+		fieldsFlag  = flag.String("fields", "", "出力するフィールド (例: name,hours)")
+		scrapeFlags = registerScraperFlags(flag.CommandLine)
+		cacheTTL    = cacheTTLFlag{ttl: defaultCacheTTL}
+	)
+	flag.Var(&cacheTTL, "cache-ttl", "取得結果のキャッシュの有効期間 (例: 6h、kaikatsu=1h)")
 	flag.Parse()
 
 	if *helpFlag {
 		fmt.Println("ネットカフェ営業時間取得ツール")
 		fmt.Println("\n使い方:")
 		fmt.Println("  ./netcafe [オプション] [検索キーワード]")
+		fmt.Println("  ./netcafe serve [-addr :8080] [-scrape] [-refresh-interval 1h]   # HTTP APIサーバーを起動")
 		fmt.Println("\nオプション:")
-		fmt.Println("  -scrape    Webサイトから最新の店舗情報を取得")
+		fmt.Println("  -scrape    Webサイトから最新の店舗情報を取得 (有効期間内ならキャッシュを使用)")
+		fmt.Println("  -refresh   キャッシュを使わずに取得し直す")
+		fmt.Println("  -cache-ttl キャッシュの有効期間 (既定 " + defaultCacheTTL.String() + "、chain=期間 で取得元ごとに指定)")
+		fmt.Println("  -base-url  取得元の接続先を差し替え (chain=URL、複数指定可)")
+		fmt.Println("  -concurrency  同時に取得する取得元の数")
+		fmt.Println("  -details n 各店舗の詳細ページから営業時間・電話番号・設備・料金を取得 (n 件ずつ並行)")
+		fmt.Println("  -max-pages 店舗一覧のページ送りをたどるページ数の上限 (既定 10)")
+		fmt.Println("  -pref      取得する都道府県 (コード・名前・ローマ字をカンマ区切り、all で全国、既定は東京都)")
+		fmt.Println("  -sources   取得元の定義 (YAML/JSON) を読み込むファイルまたはディレクトリ。同じ id の組み込み定義を置き換える")
+		fmt.Println("  -timeout   取得全体のタイムアウト (例: 30s)")
+		fmt.Println("  -rate      ホストごとの1秒あたりのリクエスト数の上限 (既定 1)")
+		fmt.Println("  -user-agent  取得時に送る User-Agent")
+		fmt.Println("  -max-attempts  タイムアウトや 429/5xx を再試行するときの試行回数の上限 (既定 3)")
+		fmt.Println("  -open-now  現在営業中の店舗だけを表示")
+		fmt.Println("  -ward      市区町村・行政区で絞り込み (例: 新宿区)")
+		fmt.Println("  -chain     チェーンで絞り込み (チェーンID か名前をカンマ区切り、例: kaikatsu,自遊空間)")
+		fmt.Println("  -format    出力形式 (" + strings.Join(outputFormats, ", ") + ")")
+		fmt.Println("  -fields    出力するフィールド (例: name,hours、text以外の形式で有効)")
 		fmt.Println("  -help      このヘルプを表示")
 		fmt.Println("\n例:")
 		fmt.Println("  ./netcafe                    # 登録済み店舗一覧を表示")
 		fmt.Println("  ./netcafe 新宿               # 「新宿」で店舗を検索")
 		fmt.Println("  ./netcafe -scrape            # Webから最新情報を取得")
 		fmt.Println("  ./netcafe -scrape 渋谷       # 最新情報から「渋谷」で検索")
-		return
+		fmt.Println("  ./netcafe -refresh           # キャッシュを無視して取得し直す")
+		fmt.Println("  ./netcafe -scrape -details 2 # 詳細ページも取得")
+		fmt.Println("  ./netcafe -scrape -pref 大阪,京都  # 大阪府と京都府の店舗を取得")
+		fmt.Println("  ./netcafe -scrape -pref all  # 全国の店舗を取得")
+		fmt.Println("  ./netcafe -open-now 新宿     # 「新宿」の営業中の店舗を検索")
+		fmt.Println("  ./netcafe -ward 新宿区       # 新宿区の店舗を表示")
+		fmt.Println("  ./netcafe -chain kaikatsu 新宿  # 快活CLUBの「新宿」の店舗を検索")
+		fmt.Println("  ./netcafe -format csv -fields name,hours  # CSVで出力")
+		fmt.Println("\n終了コード:")
+		fmt.Println("  0  正常終了")
+		fmt.Println("  1  すべての取得元で取得に失敗 (キャッシュまたはサンプルデータを表示)")
+		fmt.Println("  2  オプションの指定が不正")
+		fmt.Println("  3  一部の取得元で取得に失敗 (-details の詳細ページの一部の失敗を含む)")
+		return exitOK
+	}
+
+	if !validFormat(*formatFlag) {
+		fmt.Fprintf(os.Stderr, "不明な出力形式です: %s (%s)\n", *formatFlag, strings.Join(outputFormats, ", "))
+		return exitUsage
+	}
+	fields, err := parseFields(*fieldsFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "-fields の指定が不正です: %v\n", err)
+		return exitUsage
+	}
+	cacheDir, cacheDirErr := defaultCacheDir()
+	transport := scrapeFlags.transport(cacheDir)
+	opts, err := scrapeFlags.options(transport)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	chainIDs, err := parseChains(*chainFlag, NewScraper(opts...).chains())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "-chain の指定が不正です: %v\n", err)
+		return exitUsage
+	}
+
+	if *refreshFlag {
+		*scrapeFlag = true
+	}
+
+	// text 以外の形式では標準出力をデータ専用にし、進捗などは標準エラーに出す
+	info := io.Writer(os.Stdout)
+	if *formatFlag != formatText {
+		info = os.Stderr
 	}
 
 	var stores []NetCafe
+	exitCode := exitOK
 
 	if *scrapeFlag {
-		fmt.Println("Webサイトから最新の店舗情報を取得しています...")
-		fmt.Println(strings.Repeat("-", 50))
+		fmt.Fprintln(info, "Webサイトから最新の店舗情報を取得しています...")
+		fmt.Fprintln(info, strings.Repeat("-", 50))
 		
-		scraper := NewScraper()
-		scrapedStores, err := scraper.ScrapeAll()
-		if err != nil {
-			fmt.Printf("エラー: %v\n", err)
-			fmt.Println("サンプルデータを使用します。")
-			stores = getSampleStores()
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if *timeoutFlag > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, *timeoutFlag)
+			defer cancel()
+		}
+
+		scraper := NewScraper(opts...)
+		scrape := scraper.ScrapeAll
+		if cacheDir == "" {
+			fmt.Fprintf(info, "キャッシュを使用できません: %v\n", cacheDirErr)
 		} else {
-			stores = scrapedStores
-			fmt.Printf("\n合計 %d 店舗の情報を取得しました。\n", len(stores))
+			cache := NewSnapshotCache(filepath.Join(cacheDir, "stores.json"), cacheTTL.ttl, cacheTTL.sourceTTL)
+			scrape = func(ctx context.Context) (*ScrapeResult, error) {
+				return cache.ScrapeAll(ctx, scraper, *refreshFlag)
+			}
 		}
+		stores, exitCode = scrapeStores(ctx, info, scrape)
 	} else {
 		stores = getSampleStores()
 	}
 
-	service := &NetCafeService{
-		client: &http.Client{
-			Timeout: 10 * time.Second,
-		},
-		stores: stores,
-	}
+	service := newNetCafeService(stores)
+	service.client.Transport = transport
 
+	var cafes []NetCafe
 	args := flag.Args()
 	if len(args) > 0 {
 		keyword := strings.Join(args, " ")
-		fmt.Printf("\n「%s」で検索中...\n\n", keyword)
-		
-		results := service.SearchByName(keyword)
-		if len(results) == 0 {
-			fmt.Println("該当する店舗が見つかりませんでした。")
-			return
-		}
-		
-		fmt.Printf("%d件の店舗が見つかりました:\n", len(results))
-		for _, cafe := range results {
-			printCafe(cafe)
-		}
+		fmt.Fprintf(info, "\n「%s」で検索中...\n\n", keyword)
+		cafes = service.SearchByName(keyword)
 	} else {
 		if *scrapeFlag {
-			fmt.Println("\n取得した店舗一覧:")
+			fmt.Fprintln(info, "\n取得した店舗一覧:")
 		} else {
-			fmt.Println("ネットカフェ営業時間情報")
-			fmt.Println("使い方: ./netcafe -help でヘルプを表示")
-			fmt.Println("\n登録済み店舗一覧:")
+			fmt.Fprintln(info, "ネットカフェ営業時間情報")
+			fmt.Fprintln(info, "使い方: ./netcafe -help でヘルプを表示")
+			fmt.Fprintln(info, "\n登録済み店舗一覧:")
 		}
-		
-		for _, cafe := range service.GetAll() {
-			printCafe(cafe)
+		cafes = service.GetAll()
+	}
+
+	if *wardFlag != "" {
+		cafes = filterByWard(cafes, *wardFlag)
+	}
+	if len(chainIDs) > 0 {
+		cafes = filterByChain(cafes, chainIDs)
+	}
+	if *openNowFlag {
+		cafes = filterOpenAt(cafes, time.Now())
+	}
+
+	if len(args) > 0 {
+		if len(cafes) == 0 {
+			fmt.Fprintln(info, "該当する店舗が見つかりませんでした。")
+		} else {
+			fmt.Fprintf(info, "%d件の店舗が見つかりました:\n", len(cafes))
 		}
 	}
+
+	if err := writeCafes(os.Stdout, *formatFlag, cafes, fields); err != nil {
+		fmt.Fprintf(os.Stderr, "出力に失敗しました: %v\n", err)
+		return exitFailure
+	}
+	return exitCode
 }
\ No newline at end of file
@@ -1,10 +1,15 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -20,8 +25,14 @@ func TestNewNetCafeService(t *testing.T) {
 		t.Error("client is nil")
 	}
 	
-	if service.client.Timeout != 10*time.Second {
-		t.Errorf("expected timeout 10s, got %v", service.client.Timeout)
+	// 時間の上限は再試行の待ち時間を含めないよう試行ごとに設ける
+	if service.client.Timeout != 0 {
+		t.Errorf("expected no client-wide timeout, got %v", service.client.Timeout)
+	}
+	if retry, ok := service.client.Transport.(*RetryTransport); !ok {
+		t.Errorf("expected *RetryTransport, got %T", service.client.Transport)
+	} else if timeout, ok := retry.next.(*TimeoutTransport); !ok || timeout.timeout != 10*time.Second {
+		t.Errorf("expected a 10s timeout per attempt, got %+v", retry.next)
 	}
 	
 	if len(service.stores) == 0 {
@@ -37,11 +48,23 @@ func TestGetSampleStores(t *testing.T) {
 	}
 	
 	expectedFirstStore := NetCafe{
-		Name:     "快活CLUB 新宿西口店",
+		Name:     "新宿西口店",
+		Chain:    Chain{ID: "kaikatsu", Name: "快活CLUB"},
 		Location: "東京都新宿区西新宿1-12-9",
+		Address: &Address{
+			Prefecture: "東京都",
+			City:       "新宿区",
+			Town:       "西新宿",
+			Chome:      "1",
+			Banchi:     "12",
+			Go:         "9",
+		},
 		Hours:    "24時間営業",
-		Phone:    "03-5321-6166",
-		URL:      "https://www.kaikatsu.jp/",
+		Phone:     "03-5321-6166",
+		PhoneE164: "+81353216166",
+		URL:       "https://www.kaikatsu.jp/",
+		ID:        "kaikatsu-e544a09cde31",
+		Sources:   []string{"sample"},
 	}
 	
 	if !reflect.DeepEqual(stores[0], expectedFirstStore) {
@@ -162,4 +185,72 @@ func TestNetCafeService_SearchByLocation(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestBaseURLFlag(t *testing.T) {
+	var f baseURLFlag
+
+	if err := f.Set("kaikatsu=http://localhost:8080"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := f.Set("invalid"); err == nil {
+		t.Error("expected error for value without '='")
+	}
+
+	scraper := NewScraper(f...)
+	if got := scraper.baseURL(builtinSource(t, "kaikatsu")); got != "http://localhost:8080" {
+		t.Errorf("expected base URL from flag, got %s", got)
+	}
+}
+
+func TestScrapeStores_IncompleteDetails(t *testing.T) {
+	result := &ScrapeResult{
+		Stores: []NetCafe{{Name: "新宿西口店"}},
+		Sources: []SourceResult{{
+			Source:    "快活CLUB",
+			ChainID:   "kaikatsu",
+			Count:     1,
+			Details:   7,
+			DetailErr: &DetailError{Failed: 3, Total: 10, Err: errors.New("boom")},
+		}},
+	}
+	scrape := func(ctx context.Context) (*ScrapeResult, error) {
+		return result, scrapeError(result.Sources)
+	}
+
+	var info bytes.Buffer
+	stores, exitCode := scrapeStores(context.Background(), &info, scrape)
+	if exitCode != exitScrapePartial || len(stores) != 1 {
+		t.Errorf("expected the scraped stores with exit code %d, got %d stores, exit code %d", exitScrapePartial, len(stores), exitCode)
+	}
+	if !strings.Contains(info.String(), "詳細ページの取得に一部失敗したサイト") || !strings.Contains(info.String(), "3 of 10 detail pages failed") {
+		t.Errorf("expected detail failures in the summary:\n%s", info.String())
+	}
+}
+
+func TestRegisterScraperFlags(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	f := registerScraperFlags(fs)
+	if err := fs.Parse([]string{"-rate", "2", "-details", "4", "-base-url", "kaikatsu=http://localhost:8080"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	opts, err := f.options(http.DefaultTransport)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	scraper := NewScraper(opts...)
+	if scraper.rate != 2 || scraper.detailConcurrency != 4 {
+		t.Errorf("expected rate 2 and 4 detail workers, got %v and %d", scraper.rate, scraper.detailConcurrency)
+	}
+	if got := scraper.baseURL(builtinSource(t, "kaikatsu")); got != "http://localhost:8080" {
+		t.Errorf("expected base URL from flag, got %s", got)
+	}
+
+	if err := fs.Parse([]string{"-pref", "unknown"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := f.options(http.DefaultTransport); err == nil || !strings.HasPrefix(err.Error(), "-pref") {
+		t.Errorf("expected a -pref error, got %v", err)
+	}
+}
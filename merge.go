@@ -0,0 +1,251 @@
+package main
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// sampleSource は getSampleStores の店舗の取得元。どの取得元よりも優先度が低い。
+const sampleSource = "sample"
+
+// nameSimilarity 以上の店名は同じ店舗の表記ゆれとみなす。
+const nameSimilarity = 0.6
+
+// mergeStores は別々の取得元（または同じ取得元の別の解析結果）に載っている同じ店舗を1件にまとめる。
+// チェーンが同じ（または一方が不明）で、番地までの住所に加えて電話番号か店名の類似が一致すれば同じ店舗とみなす。
+// まとめた店舗の各フィールドは、order（ChainID の優先順）で先にある取得元の値のうち、
+// そのフィールドとして使えるもの（解析できる営業時間、有効な電話番号、店舗ページの URL など）を採る。
+// Sources には値を提供したすべての取得元が入る。店舗の順序は各店舗が最初に現れた位置のまま。
+func mergeStores(stores []NetCafe, order []string) []NetCafe {
+	parent := make([]int, len(stores))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	// 同じ店舗なら電話番号・住所・店名と所在地のいずれかが一致するので、その組だけを比べる
+	blocks := make(map[string][]int)
+	for i, c := range stores {
+		for _, key := range []string{"tel:" + phoneKey(c), "addr:" + addressKey(c), "name:" + c.Name + "\x00" + c.Location} {
+			if !strings.HasSuffix(key, ":") {
+				blocks[key] = append(blocks[key], i)
+			}
+		}
+	}
+	for _, idx := range blocks {
+		for a := 0; a < len(idx); a++ {
+			for b := a + 1; b < len(idx); b++ {
+				ra, rb := find(idx[a]), find(idx[b])
+				if ra != rb && sameStore(stores[idx[a]], stores[idx[b]]) {
+					parent[max(ra, rb)] = min(ra, rb)
+				}
+			}
+		}
+	}
+
+	groups := make(map[int][]NetCafe)
+	var roots []int
+	for i, c := range stores {
+		root := find(i)
+		if _, ok := groups[root]; !ok {
+			roots = append(roots, root)
+		}
+		groups[root] = append(groups[root], c)
+	}
+	if len(roots) == len(stores) {
+		return stores
+	}
+
+	merged := make([]NetCafe, 0, len(roots))
+	for _, root := range roots {
+		merged = append(merged, mergeGroup(groups[root], order))
+	}
+	return merged
+}
+
+// sameStore は a と b が同じ店舗を指すかどうかを返す。
+// 店名はチェーン名を除いて比べる。チェーン名まで含めると同じチェーンの別の店舗が似た店名になるため。
+// 電話番号はフリーダイヤルなどチェーンで共通のことがあるので、店名の類似だけでは同じ店舗とみなさない。
+func sameStore(a, b NetCafe) bool {
+	if a.Chain.ID != "" && b.Chain.ID != "" && a.Chain.ID != b.Chain.ID {
+		return false
+	}
+	if a.Name == b.Name && a.Location == b.Location {
+		return true
+	}
+	addr := addressKey(a) != "" && addressKey(a) == addressKey(b)
+	if !addr {
+		return false
+	}
+	phone := phoneKey(a) != "" && phoneKey(a) == phoneKey(b)
+	return phone || similarNames(a.Name, b.Name)
+}
+
+// phoneKey は正規化した電話番号。日本の電話番号として有効でなければ空。
+func phoneKey(c NetCafe) string {
+	if c.PhoneE164 != "" {
+		return c.PhoneE164
+	}
+	if p, err := ParsePhone(c.Phone); err == nil {
+		return p.E164()
+	}
+	return ""
+}
+
+// addressKey は建物名を除いた番地までの住所。番地まで分からなければ空。
+func addressKey(c NetCafe) string {
+	a := c.Address
+	if a == nil && c.Location != "" {
+		a = ParseAddress(c.Location)
+	}
+	if a == nil || a.Banchi == "" {
+		return ""
+	}
+	return strings.Join([]string{a.Prefecture + a.City + a.Ward + a.Town, a.Chome, a.Banchi, a.Go}, "-")
+}
+
+// similarNames は店名の文字 bigram の Dice 係数が nameSimilarity 以上か、一方が他方を含むかを返す。
+// 全角・半角、大文字・小文字、空白と記号の違いは無視する。
+func similarNames(a, b string) bool {
+	ra, rb := nameRunes(a), nameRunes(b)
+	if len(ra) < 2 || len(rb) < 2 {
+		return false
+	}
+	if strings.Contains(string(ra), string(rb)) || strings.Contains(string(rb), string(ra)) {
+		return true
+	}
+	bigrams := make(map[string]int)
+	for i := 0; i+1 < len(ra); i++ {
+		bigrams[string(ra[i:i+2])]++
+	}
+	common := 0
+	for i := 0; i+1 < len(rb); i++ {
+		if k := string(rb[i : i+2]); bigrams[k] > 0 {
+			bigrams[k]--
+			common++
+		}
+	}
+	return float64(2*common)/float64(len(ra)-1+len(rb)-1) >= nameSimilarity
+}
+
+func nameRunes(s string) []rune {
+	var rs []rune
+	for _, r := range strings.ToLower(normalizeWidth(s)) {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			rs = append(rs, r)
+		}
+	}
+	return rs
+}
+
+// sourceRank は c の取得元のうち最も優先度の高いものの順位。小さいほど優先する。
+func sourceRank(c NetCafe, order []string) int {
+	rank := len(order) + 1
+	for _, src := range c.Sources {
+		if i := slices.Index(order, src); i >= 0 {
+			rank = min(rank, i)
+		} else if src != sampleSource {
+			rank = min(rank, len(order))
+		}
+	}
+	return rank
+}
+
+// mergeGroup は同じ店舗のレコードをフィールドごとに優先度の高い取得元から埋めて1件にする。
+func mergeGroup(group []NetCafe, order []string) NetCafe {
+	group = slices.Clone(group)
+	slices.SortStableFunc(group, func(a, b NetCafe) int {
+		return sourceRank(a, order) - sourceRank(b, order)
+	})
+
+	var m NetCafe
+	for _, c := range group {
+		if c.Chain.ID != "" {
+			m.Chain = c.Chain
+			break
+		}
+	}
+	m.Name = pick(group, func(c NetCafe) string { return c.Name }, nil)
+	m.Location = pick(group, func(c NetCafe) string { return c.Location }, func(c NetCafe) bool {
+		return addressKey(c) != ""
+	})
+	m.Hours = pick(group, func(c NetCafe) string { return c.Hours }, func(c NetCafe) bool {
+		_, err := ParseOpeningHours(c.Hours)
+		return err == nil
+	})
+	m.Phone = pick(group, func(c NetCafe) string { return c.Phone }, func(c NetCafe) bool {
+		return phoneKey(c) != ""
+	})
+	m.URL = pick(group, func(c NetCafe) string { return c.URL }, func(c NetCafe) bool {
+		u, err := url.Parse(c.URL)
+		return err == nil && strings.Trim(u.Path, "/") != ""
+	})
+	for _, c := range group {
+		if m.Geo == nil {
+			m.Geo = c.Geo
+		}
+		if m.Prices == nil {
+			m.Prices = c.Prices
+		}
+		for _, a := range c.Amenities {
+			if !slices.Contains(m.Amenities, a) {
+				m.Amenities = append(m.Amenities, a)
+			}
+		}
+		for _, src := range c.Sources {
+			if !slices.Contains(m.Sources, src) {
+				m.Sources = append(m.Sources, src)
+			}
+		}
+	}
+	m.normalize()
+	return m
+}
+
+// pick は group の中で good を満たす最初のレコードの値を返す。なければ空でない最初の値を返す。
+func pick(group []NetCafe, value func(NetCafe) string, good func(NetCafe) bool) string {
+	if good != nil {
+		for _, c := range group {
+			if value(c) != "" && good(c) {
+				return value(c)
+			}
+		}
+	}
+	for _, c := range group {
+		if v := value(c); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// withSource は取得元の記録・チェーン・ID がない店舗に src のものを記録する。古いキャッシュの店舗に使う。
+func withSource(stores []NetCafe, src Source) []NetCafe {
+	for i := range stores {
+		stores[i].setChain(sourceChain(src))
+		if len(stores[i].Sources) == 0 {
+			stores[i].Sources = []string{src.ChainID()}
+		}
+		if stores[i].ID == "" {
+			stores[i].ID = stores[i].storeID()
+		}
+	}
+	return stores
+}
+
+// sourceOrder は s の取得元の ChainID を優先順に返す。
+func (s *Scraper) sourceOrder() []string {
+	order := make([]string, len(s.sources))
+	for i, src := range s.sources {
+		order[i] = src.ChainID()
+	}
+	return order
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func normalized(c NetCafe, sources ...string) NetCafe {
+	c.Sources = sources
+	c.normalize()
+	return c
+}
+
+func TestMergeStores(t *testing.T) {
+	stores := []NetCafe{
+		normalized(NetCafe{
+			Name:     "快活CLUB 新宿西口店",
+			Location: "東京都新宿区西新宿1-12-9",
+			Hours:    "24時間営業",
+			Phone:    "03-5321-6166",
+			URL:      "https://www.kaikatsu.jp/",
+		}, sampleSource),
+		normalized(NetCafe{
+			Name:      "快活CLUB新宿西口店",
+			Location:  "東京都新宿区西新宿一丁目12番9号 ○○ビル3F",
+			Hours:     "詳しくは店舗へお問い合わせください",
+			Phone:     "0353216166",
+			URL:       "https://www.kaikatsu.jp/shop/shinjuku/",
+			Amenities: []string{"シャワー"},
+		}, "kaikatsu"),
+		normalized(NetCafe{
+			Name:      "快活CLUB 新宿西口店",
+			Location:  "東京都新宿区西新宿1-12-9",
+			Hours:     "10:00〜翌5:00",
+			Amenities: []string{"シャワー", "駐車場"},
+		}, "manboo"),
+		// 電話番号が同じでも店名と住所が違えば別の店舗（代表番号など）
+		normalized(NetCafe{
+			Name:     "快活CLUB 池袋店",
+			Location: "東京都豊島区西池袋1-1-1",
+			Phone:    "03-5321-6166",
+		}, "kaikatsu"),
+	}
+
+	got := mergeStores(stores, []string{"kaikatsu", "manboo"})
+	if len(got) != 2 {
+		t.Fatalf("expected 2 stores, got %d: %+v", len(got), got)
+	}
+
+	// 各フィールドは優先度の高い取得元のうち使える値を採る
+	expected := normalized(NetCafe{
+		Name:      "快活CLUB新宿西口店",
+		Location:  "東京都新宿区西新宿一丁目12番9号 ○○ビル3F",
+		Hours:     "10:00〜翌5:00",
+		Phone:     "0353216166",
+		URL:       "https://www.kaikatsu.jp/shop/shinjuku/",
+		Amenities: []string{"シャワー", "駐車場"},
+	}, "kaikatsu", "manboo", sampleSource)
+	if !reflect.DeepEqual(got[0], expected) {
+		t.Errorf("unexpected merged store:\n got %+v\nwant %+v", got[0], expected)
+	}
+	if got[1].Name != "快活CLUB 池袋店" {
+		t.Errorf("stores sharing only a phone number should not be merged: %+v", got[1])
+	}
+}
+
+func TestMergeStores_NoDuplicates(t *testing.T) {
+	customa := Chain{ID: "customa", Name: "カスタマカフェ"}
+	tests := []struct {
+		name   string
+		stores []NetCafe
+	}{
+		{"different chains at the same address", []NetCafe{
+			normalized(NetCafe{Name: "秋葉原店", Chain: Chain{ID: "dice", Name: "DiCE"}, Location: "東京都千代田区外神田1-11-5"}, "dice"),
+			normalized(NetCafe{Name: "秋葉原店", Chain: Chain{ID: "aprecio", Name: "アプレシオ"}, Location: "東京都千代田区外神田1-11-5"}, "aprecio"),
+		}},
+		// フリーダイヤルはチェーンで共通なので、電話番号とチェーン名が同じだけでは同じ店舗ではない
+		{"shared toll-free number", []NetCafe{
+			normalized(NetCafe{Name: "渋谷店", Chain: customa, Location: "東京都渋谷区道玄坂2-1-1", Phone: "0120-123-456"}, "customa"),
+			normalized(NetCafe{Name: "新宿店", Chain: customa, Location: "東京都新宿区新宿3-1-1", Phone: "0120-123-456"}, "customa"),
+		}},
+		{"same chain at the same address", []NetCafe{
+			normalized(NetCafe{Name: "新宿東口店", Chain: kaikatsuChain, Location: "東京都新宿区新宿3-1-1"}, "kaikatsu"),
+			normalized(NetCafe{Name: "新宿南口店", Chain: kaikatsuChain, Location: "東京都新宿区新宿3-1-1 ○○ビル5F"}, "kaikatsu"),
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergeStores(tt.stores, nil)
+			if !reflect.DeepEqual(got, tt.stores) {
+				t.Errorf("different stores should be kept: %+v", got)
+			}
+		})
+	}
+}
+
+func TestSimilarNames(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected bool
+	}{
+		{"快活CLUB 新宿西口店", "快活ＣＬＵＢ新宿西口店", true},
+		{"新宿西口店", "快活CLUB 新宿西口店", true},
+		{"自遊空間 池袋西口ROSA店", "自遊空間 池袋西口店", true},
+		{"快活CLUB 新宿西口店", "快活CLUB 池袋店", false},
+		{"DiCE 秋葉原店", "アプレシオ 秋葉原店", false},
+		{"店", "店", false},
+	}
+	for _, tt := range tests {
+		if got := similarNames(tt.a, tt.b); got != tt.expected {
+			t.Errorf("similarNames(%q, %q) = %v, expected %v", tt.a, tt.b, got, tt.expected)
+		}
+	}
+}
+
+func TestScraper_ScrapeAll_Merge(t *testing.T) {
+	scraper := NewScraper()
+	scraper.sources = []Source{
+		fakeSource{name: "テストA", chainID: "a", cafes: []NetCafe{
+			{Name: "テスト 新宿店", Location: "東京都新宿区新宿3-1-1", Phone: "03-1234-5678"},
+			// 同じ取得元の別の解析結果に載っている同じ店舗
+			{Name: "テスト新宿店", Location: "東京都新宿区新宿三丁目1番1号", Hours: "24時間営業"},
+		}},
+		// 複数のチェーンを載せている取得元。チェーンの分かる店舗は別の取得元の店舗とまとめる
+		fakeSource{name: "テストB", chainID: "b", cafes: []NetCafe{
+			{Name: "テスト 新宿店", Chain: Chain{ID: "a", Name: "テストA"}, Location: "東京都新宿区新宿3-1-1", Phone: "03-1234-5678", URL: "http://b.example/shop/1"},
+			{Name: "テスト 渋谷店", Location: "東京都渋谷区道玄坂1-1-1"},
+		}},
+	}
+
+	result, err := scraper.ScrapeAll(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Sources[0].Count != 1 {
+		t.Errorf("expected duplicates within a source to be merged, got %d stores", result.Sources[0].Count)
+	}
+	if len(result.Stores) != 2 {
+		t.Fatalf("expected 2 stores, got %+v", result.Stores)
+	}
+	c := result.Stores[0]
+	if c.Name != "テスト 新宿店" || c.Hours != "24時間営業" || c.URL != "http://b.example/shop/1" {
+		t.Errorf("unexpected merged store: %+v", c)
+	}
+	if !reflect.DeepEqual(c.Sources, []string{"a", "b"}) {
+		t.Errorf("unexpected sources: %v", c.Sources)
+	}
+	if !reflect.DeepEqual(result.Stores[1].Sources, []string{"b"}) {
+		t.Errorf("unexpected sources: %v", result.Stores[1].Sources)
+	}
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+const (
+	formatText     = "text"
+	formatJSON     = "json"
+	formatNDJSON   = "ndjson"
+	formatCSV      = "csv"
+	formatTSV      = "tsv"
+	formatMarkdown = "markdown"
+)
+
+var outputFormats = []string{formatText, formatJSON, formatNDJSON, formatCSV, formatTSV, formatMarkdown}
+
+// cafeField は NetCafe の出力可能なフィールド。名前は JSON タグに合わせる。
+type cafeField struct {
+	name  string
+	index int
+}
+
+// cafeFields は NetCafe の JSON タグを構造体の定義順に返す。
+func cafeFields() []cafeField {
+	var fields []cafeField
+	t := reflect.TypeOf(NetCafe{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		fields = append(fields, cafeField{name: name, index: i})
+	}
+	return fields
+}
+
+// parseFields は "name,hours" のような指定を解析する。空なら nil（既定の出力）を返す。
+func parseFields(spec string) ([]cafeField, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+	all := cafeFields()
+
+	byName := make(map[string]cafeField, len(all))
+	for _, f := range all {
+		byName[f.name] = f
+	}
+
+	var fields []cafeField
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		f, ok := byName[name]
+		if !ok {
+			names := make([]string, len(all))
+			for i, f := range all {
+				names[i] = f.name
+			}
+			return nil, fmt.Errorf("unknown field %q (available: %s)", name, strings.Join(names, ", "))
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+func validFormat(format string) bool {
+	for _, f := range outputFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// writeCafes は cafes を format で w に書き出す。fields が nil の場合、JSON 系は
+// NetCafe の JSON タグどおりに、表形式は全フィールドを出力する。
+func writeCafes(w io.Writer, format string, cafes []NetCafe, fields []cafeField) error {
+	switch format {
+	case formatText:
+		return writeText(w, cafes)
+	case formatJSON:
+		items := make([]json.RawMessage, len(cafes))
+		for i, cafe := range cafes {
+			b, err := marshalCafe(cafe, fields)
+			if err != nil {
+				return err
+			}
+			items[i] = b
+		}
+		b, err := json.MarshalIndent(items, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", b)
+		return err
+	case formatNDJSON:
+		for _, cafe := range cafes {
+			b, err := marshalCafe(cafe, fields)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(w, "%s\n", b); err != nil {
+				return err
+			}
+		}
+		return nil
+	case formatCSV:
+		return writeCSV(w, cafes, tableFields(fields))
+	case formatTSV:
+		return writeTSV(w, cafes, tableFields(fields))
+	case formatMarkdown:
+		return writeMarkdown(w, cafes, tableFields(fields))
+	}
+	return fmt.Errorf("unknown format %q (available: %s)", format, strings.Join(outputFormats, ", "))
+}
+
+// writeText は店舗をチェーンごとにまとめ、チェーン名と店舗数の見出しを付けて書き出す。
+func writeText(w io.Writer, cafes []NetCafe) error {
+	cafes = groupByChain(cafes)
+	for i, cafe := range cafes {
+		if i == 0 || cafe.Chain.ID != cafes[i-1].Chain.ID {
+			n := 0
+			for _, c := range cafes[i:] {
+				if c.Chain.ID != cafe.Chain.ID {
+					break
+				}
+				n++
+			}
+			name := cafe.Chain.Name
+			if name == "" {
+				name = "その他"
+			}
+			fmt.Fprintf(w, "\n■ %s (%d店舗)\n", name, n)
+		}
+		printCafe(w, cafe)
+	}
+	return nil
+}
+
+func tableFields(fields []cafeField) []cafeField {
+	if fields == nil {
+		return cafeFields()
+	}
+	return fields
+}
+
+// marshalCafe はフィールド指定がなければ NetCafe をそのまま、あれば指定順のキーで JSON にする。
+func marshalCafe(cafe NetCafe, fields []cafeField) ([]byte, error) {
+	if fields == nil {
+		return json.Marshal(cafe)
+	}
+
+	v := reflect.ValueOf(cafe)
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range fields {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(f.name)
+		value, err := json.Marshal(v.Field(f.index).Interface())
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func tableRow(cafe NetCafe, fields []cafeField) []string {
+	v := reflect.ValueOf(cafe)
+	row := make([]string, len(fields))
+	for i, f := range fields {
+		row[i] = tableValue(v.Field(f.index))
+	}
+	return row
+}
+
+// tableValue は表形式の1セルに収まる文字列にする。スライスは ";" で連結する。
+func tableValue(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return ""
+		}
+	case reflect.Slice:
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = tableValue(v.Index(i))
+		}
+		return strings.Join(items, ";")
+	}
+	if s, ok := v.Interface().(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+func fieldNames(fields []cafeField) []string {
+	names := make([]string, len(fields))
+	for i, f := range fields {
+		names[i] = f.name
+	}
+	return names
+}
+
+func writeCSV(w io.Writer, cafes []NetCafe, fields []cafeField) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(fieldNames(fields)); err != nil {
+		return err
+	}
+	for _, cafe := range cafes {
+		if err := cw.Write(tableRow(cafe, fields)); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+var tsvReplacer = strings.NewReplacer("\t", " ", "\r\n", " ", "\n", " ", "\r", " ")
+
+func writeTSV(w io.Writer, cafes []NetCafe, fields []cafeField) error {
+	writeLine := func(cells []string) error {
+		for i := range cells {
+			cells[i] = tsvReplacer.Replace(cells[i])
+		}
+		_, err := fmt.Fprintln(w, strings.Join(cells, "\t"))
+		return err
+	}
+
+	if err := writeLine(fieldNames(fields)); err != nil {
+		return err
+	}
+	for _, cafe := range cafes {
+		if err := writeLine(tableRow(cafe, fields)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var markdownReplacer = strings.NewReplacer("|", `\|`, "\r\n", "<br>", "\n", "<br>")
+
+func writeMarkdown(w io.Writer, cafes []NetCafe, fields []cafeField) error {
+	writeLine := func(cells []string) error {
+		for i := range cells {
+			cells[i] = markdownReplacer.Replace(cells[i])
+		}
+		_, err := fmt.Fprintf(w, "| %s |\n", strings.Join(cells, " | "))
+		return err
+	}
+
+	if err := writeLine(fieldNames(fields)); err != nil {
+		return err
+	}
+	separator := make([]string, len(fields))
+	for i := range separator {
+		separator[i] = "---"
+	}
+	if err := writeLine(separator); err != nil {
+		return err
+	}
+	for _, cafe := range cafes {
+		if err := writeLine(tableRow(cafe, fields)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func testCafes() []NetCafe {
+	return []NetCafe{
+		{Name: "快活CLUB 新宿西口店", Location: "東京都新宿区西新宿1-12-9", Hours: "24時間営業", Phone: "03-5321-6166"},
+		{Name: "テスト|店", Location: "東京都渋谷区渋谷1-1-1", Hours: "10:00-22:00\t(平日)"},
+	}
+}
+
+func TestCafeFields(t *testing.T) {
+	names := fieldNames(cafeFields())
+	if strings.Join(names[:2], ",") != "name,location" {
+		t.Errorf("fields should follow struct order, got %v", names)
+	}
+	for _, name := range []string{"hours", "phone", "url"} {
+		found := false
+		for _, n := range names {
+			found = found || n == name
+		}
+		if !found {
+			t.Errorf("missing field %s in %v", name, names)
+		}
+	}
+}
+
+func TestParseFields(t *testing.T) {
+	fields, err := parseFields("hours, name")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(fieldNames(fields), ",") != "hours,name" {
+		t.Errorf("expected requested order, got %v", fieldNames(fields))
+	}
+
+	if fields, err := parseFields(""); err != nil || fields != nil {
+		t.Errorf("expected nil fields for empty spec, got %v, %v", fields, err)
+	}
+
+	if _, err := parseFields("name,unknown"); err == nil || !strings.Contains(err.Error(), "unknown") {
+		t.Errorf("expected unknown field error, got %v", err)
+	}
+}
+
+func TestWriteCafes_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeCafes(&buf, formatJSON, testCafes(), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got []NetCafe
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+	}
+	if len(got) != 2 || got[0].Name != "快活CLUB 新宿西口店" {
+		t.Errorf("unexpected decoded stores: %+v", got)
+	}
+}
+
+func TestWriteCafes_JSONEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeCafes(&buf, formatJSON, nil, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.TrimSpace(buf.String()) != "[]" {
+		t.Errorf("expected empty array, got %q", buf.String())
+	}
+}
+
+func TestWriteCafes_NDJSONFields(t *testing.T) {
+	fields, _ := parseFields("phone,name")
+
+	var buf bytes.Buffer
+	if err := writeCafes(&buf, formatNDJSON, testCafes(), fields); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(lines))
+	}
+	if lines[0] != `{"phone":"03-5321-6166","name":"快活CLUB 新宿西口店"}` {
+		t.Errorf("unexpected first line: %s", lines[0])
+	}
+	if lines[1] != `{"phone":"","name":"テスト|店"}` {
+		t.Errorf("unexpected second line: %s", lines[1])
+	}
+}
+
+func TestWriteCafes_CSV(t *testing.T) {
+	fields, _ := parseFields("name,hours")
+
+	var buf bytes.Buffer
+	if err := writeCafes(&buf, formatCSV, testCafes(), fields); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	expected := [][]string{
+		{"name", "hours"},
+		{"快活CLUB 新宿西口店", "24時間営業"},
+		{"テスト|店", "10:00-22:00\t(平日)"},
+	}
+	if len(records) != len(expected) {
+		t.Fatalf("expected %d records, got %d", len(expected), len(records))
+	}
+	for i := range expected {
+		if strings.Join(records[i], ",") != strings.Join(expected[i], ",") {
+			t.Errorf("record %d: expected %v, got %v", i, expected[i], records[i])
+		}
+	}
+}
+
+func TestWriteCafes_TSV(t *testing.T) {
+	fields, _ := parseFields("name,hours")
+
+	var buf bytes.Buffer
+	if err := writeCafes(&buf, formatTSV, testCafes(), fields); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "name\thours\n快活CLUB 新宿西口店\t24時間営業\nテスト|店\t10:00-22:00 (平日)\n"
+	if buf.String() != expected {
+		t.Errorf("unexpected TSV:\n%q\nexpected:\n%q", buf.String(), expected)
+	}
+}
+
+func TestWriteCafes_Markdown(t *testing.T) {
+	fields, _ := parseFields("name,location")
+
+	var buf bytes.Buffer
+	if err := writeCafes(&buf, formatMarkdown, testCafes(), fields); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "| name | location |\n" +
+		"| --- | --- |\n" +
+		"| 快活CLUB 新宿西口店 | 東京都新宿区西新宿1-12-9 |\n" +
+		"| テスト\\|店 | 東京都渋谷区渋谷1-1-1 |\n"
+	if buf.String() != expected {
+		t.Errorf("unexpected markdown:\n%s\nexpected:\n%s", buf.String(), expected)
+	}
+}
+
+func TestWriteCafes_AddressColumn(t *testing.T) {
+	cafe := NetCafe{Name: "A店", Location: "東京都新宿区西新宿1-12-9"}
+	cafe.normalize()
+	fields, _ := parseFields("address")
+
+	var buf bytes.Buffer
+	if err := writeCafes(&buf, formatTSV, []NetCafe{cafe, {Name: "B店"}}, fields); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buf.String() != "address\n東京都新宿区西新宿1-12-9\n\n" {
+		t.Errorf("unexpected output: %q", buf.String())
+	}
+}
+
+func TestWriteCafes_UnknownFormat(t *testing.T) {
+	if err := writeCafes(&bytes.Buffer{}, "xml", testCafes(), nil); err == nil {
+		t.Error("expected error for unknown format")
+	}
+	if validFormat("xml") || !validFormat(formatMarkdown) {
+		t.Error("validFormat returned unexpected result")
+	}
+}
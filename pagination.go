@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// defaultMaxPages は一覧ページをたどる上限。
+const defaultMaxPages = 10
+
+// WithMaxPages は店舗一覧のページ送りをたどるページ数の上限を設定する。1なら最初のページだけ取得する。
+func WithMaxPages(n int) ScraperOption {
+	return func(s *Scraper) {
+		if n > 0 {
+			s.maxPages = n
+		}
+	}
+}
+
+var (
+	// nextLinkSelectors は「次へ」リンクの候補。上から順に探す。
+	nextLinkSelectors = []string{
+		`link[rel="next"]`,
+		`a[rel="next"]`,
+		`.pagination .next a, .pagination a.next, .pager .next a, .pager-next a, a.next`,
+	}
+	nextLinkTexts = []string{"次のページ", "›", "»", "＞", ">"}
+	pageParams    = []string{"page", "p", "pg"}
+)
+
+// fetchPages は startURL から「次へ」リンクまたはページ番号のパラメータをたどり、
+// 各ページを parse に渡す。最大 s.maxPages ページで止め、取得したページ数を返す。
+// 2ページ目以降の取得に失敗した場合は、それまでのページだけで終える。
+func (s *Scraper) fetchPages(ctx context.Context, startURL string, parse func(doc *goquery.Document)) (int, error) {
+	counter := fetchCounterFrom(ctx)
+	visited := map[string]bool{}
+	pageURL := startURL
+	pages := 0
+
+	for pageURL != "" && pages < s.maxPages && !visited[pageURL] {
+		visited[pageURL] = true
+		doc, err := s.fetchDocument(ctx, pageURL)
+		if err != nil {
+			if pages == 0 {
+				return 0, err
+			}
+			log.Printf("Error scraping page %d (%s): %v", pages+1, pageURL, err)
+			break
+		}
+		pages++
+		if counter != nil {
+			counter.pages.Add(1)
+		}
+		parse(doc)
+		pageURL = nextPageURL(doc, pageURL, pages)
+	}
+	return pages, nil
+}
+
+// nextPageURL は doc の次のページの URL を返す。なければ空文字列。
+// current は doc の URL、page は doc が何ページ目か（1始まり）。
+func nextPageURL(doc *goquery.Document, current string, page int) string {
+	base, err := url.Parse(current)
+	if err != nil {
+		return ""
+	}
+	resolve := func(href string) string {
+		href = strings.TrimSpace(href)
+		if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(href, "javascript:") {
+			return ""
+		}
+		u, err := base.Parse(href)
+		if err != nil {
+			return ""
+		}
+		u.Fragment = ""
+		return u.String()
+	}
+
+	for _, sel := range nextLinkSelectors {
+		if href, ok := doc.Find(sel).First().Attr("href"); ok {
+			if u := resolve(href); u != "" {
+				return u
+			}
+		}
+	}
+
+	var next string
+	doc.Find("a[href]").EachWithBreak(func(i int, a *goquery.Selection) bool {
+		if isNextLinkText(strings.TrimSpace(a.Text())) {
+			next = resolve(a.AttrOr("href", ""))
+		}
+		return next == ""
+	})
+	if next != "" {
+		return next
+	}
+
+	// 「次へ」がなければ ?page=N+1 のようなページ番号のリンクを探す
+	want := strconv.Itoa(page + 1)
+	doc.Find("a[href]").EachWithBreak(func(i int, a *goquery.Selection) bool {
+		u, err := base.Parse(a.AttrOr("href", ""))
+		if err != nil || u.Path != base.Path {
+			return true
+		}
+		for _, p := range pageParams {
+			if u.Query().Get(p) == want {
+				u.Fragment = ""
+				next = u.String()
+				return false
+			}
+		}
+		return true
+	})
+	return next
+}
+
+func isNextLinkText(text string) bool {
+	if strings.HasPrefix(text, "次へ") {
+		return true
+	}
+	for _, t := range nextLinkTexts {
+		if text == t {
+			return true
+		}
+	}
+	return false
+}
+
+// dedupeCafes は複数ページに重複して載っている店舗を、名前と所在地が同じものを1件にまとめる。
+func dedupeCafes(cafes []NetCafe) []NetCafe {
+	seen := make(map[string]bool, len(cafes))
+	var results []NetCafe
+	for _, c := range cafes {
+		key := c.Name + "\x00" + c.Location
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		results = append(results, c)
+	}
+	return results
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestScraper_ScrapeAll_Pagination(t *testing.T) {
+	pages := map[string]string{
+		"/shop/?pref=13": `
+			<div class="shop-item"><h3 class="shop-name">池袋西口ROSA店</h3><div class="shop-address">東京都豊島区西池袋1-37-12</div></div>
+			<div class="pagination"><a href="?pref=13&amp;page=2">次へ &gt;</a></div>`,
+		"/shop/?pref=13&page=2": `
+			<div class="shop-item"><h3 class="shop-name">新宿東口店</h3><div class="shop-address">東京都新宿区新宿3-1-1</div></div>
+			<div class="shop-item"><h3 class="shop-name">池袋西口ROSA店</h3><div class="shop-address">東京都豊島区西池袋1-37-12</div></div>
+			<div class="pagination"><a rel="next" href="/shop/?pref=13&amp;page=3">3</a></div>`,
+		"/shop/?pref=13&page=3": `
+			<div class="shop-item"><h3 class="shop-name">渋谷店</h3><div class="shop-address">東京都渋谷区渋谷1-1-1</div></div>`,
+	}
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.RequestURI())
+		body, ok := pages[r.URL.RequestURI()]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("<html><body>" + body + "</body></html>"))
+	}))
+	defer server.Close()
+
+	scraper := NewScraper(WithBaseURL("jiqoo", server.URL), WithTransport(http.DefaultTransport))
+	scraper.sources = []Source{builtinSource(t, "jiqoo")}
+
+	result, err := scraper.ScrapeAll(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r := result.Sources[0]; r.Pages != 3 || r.Count != 3 {
+		t.Errorf("expected 3 pages and 3 unique stores, got pages=%d count=%d (%v)", r.Pages, r.Count, requests)
+	}
+}
+
+func TestScraper_FetchPages_PageNumbers(t *testing.T) {
+	// 「次へ」リンクがなく、ページ番号のリンクだけが並ぶ一覧
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("page")
+		if page == "" {
+			page = "1"
+		}
+		var links strings.Builder
+		for i := 1; i <= 5; i++ {
+			fmt.Fprintf(&links, `<a href="/shop/tokyo/?page=%d">%d</a>`, i, i)
+		}
+		fmt.Fprintf(w, `<ul><li class="shop-list-item"><h3>%s号店</h3></li></ul><div class="pager">%s</div>`, page, links.String())
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		maxPages int
+		expected int
+	}{
+		{maxPages: 10, expected: 5},
+		{maxPages: 2, expected: 2},
+		{maxPages: 1, expected: 1},
+	}
+	for _, tt := range tests {
+		scraper := NewScraper(WithBaseURL("kaikatsu", server.URL), WithTransport(http.DefaultTransport), WithMaxPages(tt.maxPages))
+		cafes, err := builtinSource(t, "kaikatsu").Scrape(context.Background(), scraper)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(cafes) != tt.expected {
+			t.Errorf("max pages %d: expected %d stores, got %d", tt.maxPages, tt.expected, len(cafes))
+		}
+	}
+}
+
+func TestScraper_FetchPages_Loop(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		// 2ページ目の「次へ」が1ページ目を指している
+		next := "/list?page=2"
+		if r.URL.Query().Get("page") == "2" {
+			next = "/list"
+		}
+		fmt.Fprintf(w, `<a rel="next" href="%s">次へ</a>`, next)
+	}))
+	defer server.Close()
+
+	scraper := NewScraper(WithTransport(http.DefaultTransport))
+	pages, err := scraper.fetchPages(context.Background(), server.URL+"/list", func(*goquery.Document) {})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pages != 2 || requests != 2 {
+		t.Errorf("expected to stop at a visited page, pages=%d requests=%d", pages, requests)
+	}
+}
+
+func TestScraper_FetchPages_LaterPageFails(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`<div class="shop-item"><h3 class="shop-name">池袋店</h3></div><a href="?pref=13&amp;page=2">次へ</a>`))
+	}))
+	defer server.Close()
+
+	scraper := NewScraper(WithBaseURL("jiqoo", server.URL), WithTransport(http.DefaultTransport))
+	cafes, err := builtinSource(t, "jiqoo").Scrape(context.Background(), scraper)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cafes) != 1 {
+		t.Errorf("expected stores from the first page, got %+v", cafes)
+	}
+}
+
+func TestNextPageURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		html     string
+		current  string
+		page     int
+		expected string
+	}{
+		{"link rel", `<head><link rel="next" href="/shop/?page=2"></head>`, "https://example.com/shop/", 1, "https://example.com/shop/?page=2"},
+		{"next class", `<ul class="pagination"><li class="next"><a href="page/3/">›</a></li></ul>`, "https://example.com/shop/page/2/", 2, "https://example.com/shop/page/2/page/3/"},
+		{"next text", `<a href="list2.html#top">次へ</a>`, "https://example.com/shop/list1.html", 1, "https://example.com/shop/list2.html"},
+		{"page number", `<a href="?pref=13&p=1">1</a><a href="?pref=13&p=2">2</a>`, "https://example.com/shop/?pref=13", 1, "https://example.com/shop/?pref=13&p=2"},
+		{"other path", `<a href="/news/?page=2">2</a>`, "https://example.com/shop/", 1, ""},
+		{"last page", `<a href="?page=1">1</a><a href="?page=2">2</a>`, "https://example.com/shop/?page=2", 2, ""},
+		{"javascript", `<a href="javascript:void(0)">次へ</a>`, "https://example.com/shop/", 1, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(tt.html))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := nextPageURL(doc, tt.current, tt.page); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestDedupeCafes(t *testing.T) {
+	cafes := []NetCafe{
+		{Name: "A店", Location: "東京都新宿区"},
+		{Name: "B店", Location: "東京都渋谷区"},
+		{Name: "A店", Location: "東京都新宿区"},
+		{Name: "A店", Location: "東京都豊島区"},
+	}
+	got := dedupeCafes(cafes)
+	if len(got) != 3 || got[0].Name != "A店" || got[1].Name != "B店" || got[2].Location != "東京都豊島区" {
+		t.Errorf("unexpected result: %+v", got)
+	}
+}
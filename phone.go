@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+var ErrInvalidPhone = errors.New("invalid phone number")
+
+// PhoneNumber は正規化した日本の電話番号。
+type PhoneNumber struct {
+	// Digits は先頭の0を含む国内表記の数字列（例: 0353216166）。
+	Digits string
+	groups []string
+}
+
+var (
+	phoneCandidatePattern = regexp.MustCompile(`(?:\+81|0)[\d\-\s().]{7,}\d`)
+	phonePrefixReplacer   = strings.NewReplacer("TEL", "", "Tel", "", "tel", "", "電話番号", "", "電話", "", "☎", "", "℡", "")
+	phoneSeparatorPattern = regexp.MustCompile(`[\-\s().]+`)
+)
+
+// ParsePhone は「03-5321-6166」「(03)5321-6166」「TEL：０３－５３２１－６１６６」「+81 3 5321 6166」
+// のような表記を解析する。日本の番号として桁数が合わない場合は ErrInvalidPhone を返す。
+func ParsePhone(s string) (PhoneNumber, error) {
+	s = normalizeDashes(normalizeWidth(s))
+	s = phonePrefixReplacer.Replace(s)
+	s = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(s), ":"))
+
+	candidate := phoneCandidatePattern.FindString(s)
+	if candidate == "" {
+		return PhoneNumber{}, ErrInvalidPhone
+	}
+
+	international := strings.HasPrefix(candidate, "+81")
+	if international {
+		candidate = strings.TrimPrefix(candidate, "+81")
+		// 「+81 (0)3-...」のように括弧付きで市外局番の0を残す表記
+		candidate = strings.Replace(candidate, "(0)", "", 1)
+	}
+
+	var groups []string
+	for _, g := range phoneSeparatorPattern.Split(candidate, -1) {
+		if g != "" {
+			groups = append(groups, g)
+		}
+	}
+	digits := strings.Join(groups, "")
+	if international {
+		digits = "0" + digits
+		if len(groups) > 0 {
+			groups[0] = "0" + groups[0]
+		}
+	}
+
+	if !validJapanesePhone(digits) {
+		return PhoneNumber{}, ErrInvalidPhone
+	}
+
+	p := PhoneNumber{Digits: digits}
+	if len(groups) == 3 {
+		p.groups = groups
+	}
+	return p, nil
+}
+
+// elevenDigitPrefixes は11桁になる番号（携帯・IP電話・0800）の先頭。
+var elevenDigitPrefixes = []string{"050", "070", "080", "090", "0800"}
+
+func validJapanesePhone(digits string) bool {
+	if !strings.HasPrefix(digits, "0") || digits[1] == '0' {
+		return false
+	}
+	eleven := false
+	for _, prefix := range elevenDigitPrefixes {
+		if strings.HasPrefix(digits, prefix) {
+			eleven = true
+		}
+	}
+	if eleven {
+		return len(digits) == 11
+	}
+	return len(digits) == 10
+}
+
+// National はハイフン区切りの国内表記を返す。元の表記が3つに区切られていればその区切りを使い、
+// そうでなければ番号の種類から区切り位置を推定する。
+func (p PhoneNumber) National() string {
+	if p.groups != nil {
+		return strings.Join(p.groups, "-")
+	}
+
+	d := p.Digits
+	var sizes []int
+	switch {
+	case strings.HasPrefix(d, "0120"), strings.HasPrefix(d, "0570"):
+		sizes = []int{4, 3, 3}
+	case strings.HasPrefix(d, "0800"):
+		sizes = []int{4, 3, 4}
+	case len(d) == 11:
+		sizes = []int{3, 4, 4}
+	case strings.HasPrefix(d, "03"), strings.HasPrefix(d, "06"):
+		sizes = []int{2, 4, 4}
+	default:
+		sizes = []int{3, 3, 4}
+	}
+
+	parts := make([]string, 0, len(sizes))
+	for _, n := range sizes {
+		parts = append(parts, d[:n])
+		d = d[n:]
+	}
+	return strings.Join(parts, "-")
+}
+
+// E164 は国際表記（例: +81353216166）を返す。
+func (p PhoneNumber) E164() string {
+	return "+81" + p.Digits[1:]
+}
+
+// phoneQueryDigits は検索キーワードが電話番号らしければ国内表記の数字列を返す。
+func phoneQueryDigits(keyword string) (string, bool) {
+	s := phoneSeparatorPattern.ReplaceAllString(normalizeDashes(normalizeWidth(keyword)), "")
+	if strings.HasPrefix(s, "+81") {
+		s = "0" + strings.TrimPrefix(s, "+81")
+	}
+	if len(s) < 4 || strings.Trim(s, "0123456789") != "" {
+		return "", false
+	}
+	return s, true
+}
+
+// matchesPhone は店舗の電話番号が表記ゆれを無視して digits を含むかどうかを返す。
+func (c NetCafe) matchesPhone(digits string) bool {
+	if p, err := ParsePhone(c.Phone); err == nil {
+		return strings.Contains(p.Digits, digits)
+	}
+	return false
+}
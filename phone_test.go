@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParsePhone(t *testing.T) {
+	tests := []struct {
+		input    string
+		national string
+		e164     string
+	}{
+		{"03-5321-6166", "03-5321-6166", "+81353216166"},
+		{"(03)5321-6166", "03-5321-6166", "+81353216166"},
+		{"TEL：０３－５３２１－６１６６", "03-5321-6166", "+81353216166"},
+		{"Tel: 0353216166", "03-5321-6166", "+81353216166"},
+		{"+81 3 5321 6166", "03-5321-6166", "+81353216166"},
+		{"+81 (0)3-5321-6166", "03-5321-6166", "+81353216166"},
+		{"045 (123) 4567", "045-123-4567", "+81451234567"},
+		{"0467-12-3456", "0467-12-3456", "+81467123456"},
+		{"09012345678", "090-1234-5678", "+819012345678"},
+		{"0120123456", "0120-123-456", "+81120123456"},
+		{"電話番号 03−1234−5678（代表）", "03-1234-5678", "+81312345678"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			p, err := ParsePhone(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := p.National(); got != tt.national {
+				t.Errorf("National: expected %s, got %s", tt.national, got)
+			}
+			if got := p.E164(); got != tt.e164 {
+				t.Errorf("E164: expected %s, got %s", tt.e164, got)
+			}
+		})
+	}
+}
+
+func TestParsePhone_Invalid(t *testing.T) {
+	for _, input := range []string{"", "営業時間外", "03-1234", "1234-5678-9012", "00-1234-5678", "090-1234-567"} {
+		if _, err := ParsePhone(input); !errors.Is(err, ErrInvalidPhone) {
+			t.Errorf("ParsePhone(%q): expected ErrInvalidPhone, got %v", input, err)
+		}
+	}
+}
+
+func TestNetCafe_normalize_Phone(t *testing.T) {
+	cafe := NetCafe{Phone: "TEL.(03)5321-6166"}
+	cafe.normalize()
+	if cafe.Phone != "03-5321-6166" || cafe.PhoneE164 != "+81353216166" {
+		t.Errorf("unexpected normalized phone: %s / %s", cafe.Phone, cafe.PhoneE164)
+	}
+
+	invalid := NetCafe{Phone: "非公開"}
+	invalid.normalize()
+	if invalid.Phone != "非公開" || invalid.PhoneE164 != "" {
+		t.Errorf("invalid phone should be kept as-is: %s / %s", invalid.Phone, invalid.PhoneE164)
+	}
+}
+
+func TestNetCafeService_SearchByPhone(t *testing.T) {
+	service := NewNetCafeService()
+
+	for _, keyword := range []string{"0353216166", "(03)5321-6166", "+81 3 5321 6166", "５３２１－６１６６"} {
+		results := service.SearchByName(keyword)
+		if len(results) != 1 || results[0].Name != "新宿西口店" {
+			t.Errorf("unexpected results for %q: %+v", keyword, results)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultUserAgent = "netcafe-go/1.0 (+https://github.com/ryuichi1208/netcafe-go)"
+	// defaultRateLimit はホストごとの1秒あたりのリクエスト数の上限。
+	defaultRateLimit = 1.0
+	robotsTTL        = 24 * time.Hour
+)
+
+// ErrDisallowedByRobots は robots.txt で取得が禁止されている URL へのリクエストで返る。
+var ErrDisallowedByRobots = errors.New("disallowed by robots.txt")
+
+// PoliteTransport はホストごとのレート制限と robots.txt の確認をし、User-Agent を付けて
+// next にリクエストを渡す RoundTripper。robots.txt の Crawl-delay がレート制限より
+// 厳しければそちらに合わせる。
+type PoliteTransport struct {
+	next      http.RoundTripper
+	userAgent string
+	rate      float64
+	now       func() time.Time
+
+	mu    sync.Mutex
+	hosts map[string]*politeHost
+}
+
+type politeHost struct {
+	// mu は robots.txt の取得を1回にまとめるためのロック。
+	mu        sync.Mutex
+	robots    *robotsRules
+	fetchedAt time.Time
+	limiter   *tokenBucket
+}
+
+// NewPoliteTransport はホストごとに毎秒 rate 回までにリクエストを絞る PoliteTransport を返す。
+// next が nil なら http.DefaultTransport、userAgent が空なら defaultUserAgent を使う。
+func NewPoliteTransport(next http.RoundTripper, userAgent string, rate float64) *PoliteTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if userAgent == "" {
+		userAgent = defaultUserAgent
+	}
+	if rate <= 0 {
+		rate = defaultRateLimit
+	}
+	return &PoliteTransport{
+		next:      next,
+		userAgent: userAgent,
+		rate:      rate,
+		now:       time.Now,
+		hosts:     make(map[string]*politeHost),
+	}
+}
+
+func (t *PoliteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := t.host(req.URL.Host)
+
+	rules, err := t.robots(req, host)
+	if err != nil {
+		return nil, err
+	}
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if req.URL.RawQuery != "" {
+		path += "?" + req.URL.RawQuery
+	}
+	if !rules.Allowed(path) {
+		return nil, fmt.Errorf("%w: %s", ErrDisallowedByRobots, req.URL)
+	}
+
+	if err := host.limiter.wait(req.Context()); err != nil {
+		return nil, err
+	}
+	return t.next.RoundTrip(t.withUserAgent(req))
+}
+
+func (t *PoliteTransport) host(name string) *politeHost {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	h, ok := t.hosts[name]
+	if !ok {
+		h = &politeHost{limiter: newTokenBucket(t.rate, 1)}
+		t.hosts[name] = h
+	}
+	return h
+}
+
+func (t *PoliteTransport) withUserAgent(req *http.Request) *http.Request {
+	if req.Header.Get("User-Agent") != "" {
+		return req
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", t.userAgent)
+	return req
+}
+
+// robots はホストの robots.txt を robotsTTL の間メモリに保持する。
+// RFC 9309 に従い、リダイレクト（3xx）や見つからない（4xx）場合はすべて許可し、
+// 5xx や通信エラーの場合はリクエストを失敗させる。
+func (t *PoliteTransport) robots(req *http.Request, host *politeHost) (*robotsRules, error) {
+	host.mu.Lock()
+	defer host.mu.Unlock()
+
+	if host.robots != nil && t.now().Sub(host.fetchedAt) < robotsTTL {
+		return host.robots, nil
+	}
+
+	robotsURL := *req.URL
+	robotsURL.Path, robotsURL.RawPath, robotsURL.RawQuery, robotsURL.Fragment = "/robots.txt", "", "", ""
+	robotsReq, err := http.NewRequestWithContext(req.Context(), http.MethodGet, robotsURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	robotsReq.Header.Set("User-Agent", t.userAgent)
+
+	resp, err := t.next.RoundTrip(robotsReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch robots.txt: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 500:
+		return nil, fmt.Errorf("failed to fetch robots.txt: status %d", resp.StatusCode)
+	case resp.StatusCode >= 300:
+		host.robots = &robotsRules{}
+	default:
+		host.robots = parseRobots(resp.Body, t.userAgent)
+	}
+	host.fetchedAt = t.now()
+
+	if d := host.robots.crawlDelay; d > 0 {
+		host.limiter.limit(float64(time.Second) / float64(d))
+	}
+	return host.robots, nil
+}
+
+// tokenBucket は毎秒 rate 個ずつ、最大 burst 個までトークンがたまるレート制限。
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	return &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// limit は rate が現在の速度より遅ければ rate に下げる。
+func (b *tokenBucket) limit(rate float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if rate < b.rate {
+		b.rate = rate
+	}
+}
+
+// wait はトークンをひとつ取り出せるまで待つ。ctx が終われば ctx.Err() を返す。
+func (b *tokenBucket) wait(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+		b.last = now
+		if b.tokens >= 1 {
+			b.tokens--
+			b.mu.Unlock()
+			return nil
+		}
+		delay := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		b.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// robotsServer は robots.txt と任意のページを返すテスト用サーバー。
+func robotsServer(t *testing.T, robotsStatus int, robotsTxt string) (*httptest.Server, *requestLog) {
+	t.Helper()
+	log := &requestLog{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.add(r)
+		if r.URL.Path == "/robots.txt" {
+			w.WriteHeader(robotsStatus)
+			w.Write([]byte(robotsTxt))
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	t.Cleanup(server.Close)
+	return server, log
+}
+
+type requestLog struct {
+	mu         sync.Mutex
+	paths      []string
+	userAgents []string
+	times      []time.Time
+}
+
+func (l *requestLog) add(r *http.Request) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.paths = append(l.paths, r.URL.RequestURI())
+	l.userAgents = append(l.userAgents, r.UserAgent())
+	l.times = append(l.times, time.Now())
+}
+
+func TestPoliteTransport_Robots(t *testing.T) {
+	server, log := robotsServer(t, http.StatusOK, "User-agent: *\nDisallow: /private/\n")
+	client := &http.Client{Transport: NewPoliteTransport(nil, "", 100)}
+
+	resp, err := client.Get(server.URL + "/shop/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+
+	_, err = client.Get(server.URL + "/private/list")
+	if !errors.Is(err, ErrDisallowedByRobots) {
+		t.Errorf("expected ErrDisallowedByRobots, got %v", err)
+	}
+
+	// robots.txt はホストごとに一度だけ取得し、禁止されたページは送らない
+	expected := []string{"/robots.txt", "/shop/"}
+	if len(log.paths) != len(expected) || log.paths[0] != expected[0] || log.paths[1] != expected[1] {
+		t.Errorf("unexpected requests: %v", log.paths)
+	}
+	for _, ua := range log.userAgents {
+		if ua != defaultUserAgent {
+			t.Errorf("expected User-Agent %q, got %q", defaultUserAgent, ua)
+		}
+	}
+}
+
+func TestPoliteTransport_RobotsRoot(t *testing.T) {
+	server, log := robotsServer(t, http.StatusOK, "User-agent: *\nDisallow: /\n")
+	client := &http.Client{Transport: NewPoliteTransport(nil, "", 100)}
+
+	// パスのない URL は "/" として判定する
+	if _, err := client.Get(server.URL); !errors.Is(err, ErrDisallowedByRobots) {
+		t.Errorf("expected ErrDisallowedByRobots, got %v", err)
+	}
+	if len(log.paths) != 1 {
+		t.Errorf("page should not be requested, got %v", log.paths)
+	}
+}
+
+func TestPoliteTransport_RobotsStatus(t *testing.T) {
+	t.Run("not found allows all", func(t *testing.T) {
+		server, _ := robotsServer(t, http.StatusNotFound, "")
+		client := &http.Client{Transport: NewPoliteTransport(nil, "", 100)}
+		resp, err := client.Get(server.URL + "/private/")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp.Body.Close()
+	})
+
+	t.Run("redirect allows all", func(t *testing.T) {
+		// リダイレクトの本文は robots.txt として解釈しない
+		server, _ := robotsServer(t, http.StatusMovedPermanently, "User-agent: *\nDisallow: /\n")
+		client := &http.Client{Transport: NewPoliteTransport(nil, "", 100)}
+		resp, err := client.Get(server.URL + "/private/")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp.Body.Close()
+	})
+
+	t.Run("server error blocks", func(t *testing.T) {
+		server, log := robotsServer(t, http.StatusServiceUnavailable, "")
+		client := &http.Client{Transport: NewPoliteTransport(nil, "", 100)}
+		if _, err := client.Get(server.URL + "/shop/"); err == nil {
+			t.Error("expected error when robots.txt is unavailable")
+		}
+		if len(log.paths) != 1 {
+			t.Errorf("page should not be requested, got %v", log.paths)
+		}
+	})
+}
+
+func TestPoliteTransport_UserAgent(t *testing.T) {
+	server, log := robotsServer(t, http.StatusNotFound, "")
+	client := &http.Client{Transport: NewPoliteTransport(nil, "my-crawler/0.1", 100)}
+
+	resp, err := client.Get(server.URL + "/shop/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+
+	for _, ua := range log.userAgents {
+		if ua != "my-crawler/0.1" {
+			t.Errorf("expected configured User-Agent, got %q", ua)
+		}
+	}
+}
+
+func TestPoliteTransport_CrawlDelay(t *testing.T) {
+	server, log := robotsServer(t, http.StatusOK, "User-agent: *\nCrawl-delay: 0.1\n")
+	client := &http.Client{Transport: NewPoliteTransport(nil, "", 100)}
+
+	for i := 0; i < 3; i++ {
+		resp, err := client.Get(server.URL + "/shop/")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	// robots.txt を除く3回のリクエストは Crawl-delay の間隔をあける
+	pages := log.times[1:]
+	for i := 1; i < len(pages); i++ {
+		if gap := pages[i].Sub(pages[i-1]); gap < 90*time.Millisecond {
+			t.Errorf("requests %d and %d were %v apart, expected at least the crawl delay", i-1, i, gap)
+		}
+	}
+}
+
+func TestTokenBucket_Wait(t *testing.T) {
+	b := newTokenBucket(20, 1)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := b.wait(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	// 最初の1回はすぐ、残り2回は 1/20 秒ずつ待つ
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("expected rate limiting, took %v", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	b.limit(0.001)
+	if err := b.wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// Prefecture は都道府県。Code は JIS X 0401 の都道府県コード（1〜47）。
+type Prefecture struct {
+	Code int
+	Name string
+	// Slug は URL に使われるローマ字表記（例: tokyo）。
+	Slug string
+}
+
+var prefectures = []Prefecture{
+	{1, "北海道", "hokkaido"}, {2, "青森県", "aomori"}, {3, "岩手県", "iwate"},
+	{4, "宮城県", "miyagi"}, {5, "秋田県", "akita"}, {6, "山形県", "yamagata"},
+	{7, "福島県", "fukushima"}, {8, "茨城県", "ibaraki"}, {9, "栃木県", "tochigi"},
+	{10, "群馬県", "gunma"}, {11, "埼玉県", "saitama"}, {12, "千葉県", "chiba"},
+	{13, "東京都", "tokyo"}, {14, "神奈川県", "kanagawa"}, {15, "新潟県", "niigata"},
+	{16, "富山県", "toyama"}, {17, "石川県", "ishikawa"}, {18, "福井県", "fukui"},
+	{19, "山梨県", "yamanashi"}, {20, "長野県", "nagano"}, {21, "岐阜県", "gifu"},
+	{22, "静岡県", "shizuoka"}, {23, "愛知県", "aichi"}, {24, "三重県", "mie"},
+	{25, "滋賀県", "shiga"}, {26, "京都府", "kyoto"}, {27, "大阪府", "osaka"},
+	{28, "兵庫県", "hyogo"}, {29, "奈良県", "nara"}, {30, "和歌山県", "wakayama"},
+	{31, "鳥取県", "tottori"}, {32, "島根県", "shimane"}, {33, "岡山県", "okayama"},
+	{34, "広島県", "hiroshima"}, {35, "山口県", "yamaguchi"}, {36, "徳島県", "tokushima"},
+	{37, "香川県", "kagawa"}, {38, "愛媛県", "ehime"}, {39, "高知県", "kochi"},
+	{40, "福岡県", "fukuoka"}, {41, "佐賀県", "saga"}, {42, "長崎県", "nagasaki"},
+	{43, "熊本県", "kumamoto"}, {44, "大分県", "oita"}, {45, "宮崎県", "miyazaki"},
+	{46, "鹿児島県", "kagoshima"}, {47, "沖縄県", "okinawa"},
+}
+
+// tokyo は -pref を指定しないときの対象。
+var tokyo = prefectures[12]
+
+// ShortName は「都」「府」「県」を除いた名前（例: 東京）を返す。北海道はそのまま。
+func (p Prefecture) ShortName() string {
+	if p.Name == "北海道" {
+		return p.Name
+	}
+	for _, suffix := range []string{"都", "府", "県"} {
+		if name, ok := strings.CutSuffix(p.Name, suffix); ok {
+			return name
+		}
+	}
+	return p.Name
+}
+
+// CodeString は2桁の都道府県コード（例: 01、13）を返す。
+func (p Prefecture) CodeString() string {
+	return fmt.Sprintf("%02d", p.Code)
+}
+
+// mentionedIn は text にこの都道府県名が含まれるかどうかを返す。
+// 「東京都」の中の「京都」のような部分一致は除く。
+func (p Prefecture) mentionedIn(text string) bool {
+	if strings.Contains(text, p.Name) {
+		return true
+	}
+	short := p.ShortName()
+	for i := 0; ; {
+		j := strings.Index(text[i:], short)
+		if j < 0 {
+			return false
+		}
+		j += i
+		if !strings.HasSuffix(text[:j], "東") {
+			return true
+		}
+		i = j + len(short)
+	}
+}
+
+// locatedIn は店舗がこの都道府県にあるかどうかを返す。住所 address があれば先頭の都道府県
+// （「大阪市北区」のように都道府県を省いた住所なら市区町村名の先頭）で判定し、
+// 「横浜市中区石川町」が石川県に当たるような住所の途中との一致は数えない。
+// 住所がなければ text に都道府県名が含まれるかどうかで判定する。
+func (p Prefecture) locatedIn(address, text string) bool {
+	if strings.TrimSpace(address) == "" {
+		return p.mentionedIn(text)
+	}
+	a := ParseAddress(address)
+	if a.Prefecture != "" {
+		return a.Prefecture == p.Name
+	}
+	return a.City != "" && strings.HasPrefix(a.City, p.ShortName())
+}
+
+// locatedInAny は店舗が prefs のいずれかの都道府県にあるかどうかを返す（locatedIn）。
+func locatedInAny(address, text string, prefs []Prefecture) bool {
+	for _, p := range prefs {
+		if p.locatedIn(address, text) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParsePrefecture は「13」「01」「東京都」「東京」「tokyo」のいずれかの表記を解析する。
+func ParsePrefecture(s string) (Prefecture, error) {
+	s = strings.TrimSpace(normalizeWidth(s))
+	if code, err := strconv.Atoi(s); err == nil {
+		if code >= 1 && code <= len(prefectures) {
+			return prefectures[code-1], nil
+		}
+		return Prefecture{}, fmt.Errorf("unknown prefecture code %q (01-47)", s)
+	}
+	for _, p := range prefectures {
+		if s == p.Name || s == p.ShortName() || strings.EqualFold(s, p.Slug) {
+			return p, nil
+		}
+	}
+	return Prefecture{}, fmt.Errorf("unknown prefecture %q", s)
+}
+
+// parsePrefectures は -pref の「13,大阪,kanagawa」のようなカンマ区切りの指定を解析する。
+// "all" はすべての都道府県を表す。空なら東京都だけを返す。
+func parsePrefectures(spec string) ([]Prefecture, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return []Prefecture{tokyo}, nil
+	}
+	if strings.EqualFold(spec, "all") {
+		return append([]Prefecture(nil), prefectures...), nil
+	}
+
+	seen := map[int]bool{}
+	var prefs []Prefecture
+	for _, item := range strings.Split(spec, ",") {
+		p, err := ParsePrefecture(item)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[p.Code] {
+			seen[p.Code] = true
+			prefs = append(prefs, p)
+		}
+	}
+	return prefs, nil
+}
+
+// WithPrefectures は取得する都道府県を設定する。既定は東京都だけ。
+func WithPrefectures(prefs ...Prefecture) ScraperOption {
+	return func(s *Scraper) {
+		if len(prefs) > 0 {
+			s.prefectures = prefs
+		}
+	}
+}
+
+// prefectureKey は対象の都道府県をキャッシュのキーなどに使う文字列（例: 13,27）にする。
+func prefectureKey(prefs []Prefecture) string {
+	codes := make([]string, len(prefs))
+	for i, p := range prefs {
+		codes[i] = p.CodeString()
+	}
+	return strings.Join(codes, ",")
+}
+
+// scrapePrefectures は対象の都道府県ごとに scrape を呼び、結果をつなげて返す。
+// 一部の都道府県だけ失敗した場合（店舗のない県の一覧が 404 になるなど）はログに残して続け、
+// すべて失敗した場合は最初のエラーを返す。
+func (s *Scraper) scrapePrefectures(ctx context.Context, scrape func(pref Prefecture) ([]NetCafe, error)) ([]NetCafe, error) {
+	var cafes []NetCafe
+	var firstErr error
+	failed := 0
+	for _, pref := range s.prefectures {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		found, err := scrape(pref)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			failed++
+			if len(s.prefectures) > 1 {
+				log.Printf("Error scraping %s: %v", pref.Name, err)
+			}
+			continue
+		}
+		cafes = append(cafes, found...)
+	}
+	if failed == len(s.prefectures) {
+		return nil, firstErr
+	}
+	return dedupeCafes(cafes), nil
+}
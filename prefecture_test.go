@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestParsePrefecture(t *testing.T) {
+	tests := map[string]int{
+		"13":        13,
+		"01":        1,
+		"１３":        13,
+		"東京都":       13,
+		"東京":        13,
+		"大阪":        27,
+		"京都府":       26,
+		"京都":        26,
+		"北海道":       1,
+		"Kanagawa":  14,
+		" okinawa ": 47,
+	}
+	for input, code := range tests {
+		p, err := ParsePrefecture(input)
+		if err != nil {
+			t.Errorf("ParsePrefecture(%q) returned error: %v", input, err)
+			continue
+		}
+		if p.Code != code {
+			t.Errorf("ParsePrefecture(%q) = %d, expected %d", input, p.Code, code)
+		}
+	}
+
+	for _, input := range []string{"0", "48", "東京府", "", "edo"} {
+		if _, err := ParsePrefecture(input); err == nil {
+			t.Errorf("ParsePrefecture(%q) should fail", input)
+		}
+	}
+}
+
+func TestParsePrefectures(t *testing.T) {
+	prefs, err := parsePrefectures("")
+	if err != nil || prefectureKey(prefs) != "13" {
+		t.Errorf("expected Tokyo by default, got %v, %v", prefs, err)
+	}
+
+	prefs, err = parsePrefectures("all")
+	if err != nil || len(prefs) != 47 || prefs[0].Name != "北海道" || prefs[46].Name != "沖縄県" {
+		t.Errorf("expected all 47 prefectures, got %d, %v", len(prefs), err)
+	}
+
+	prefs, err = parsePrefectures("大阪, 27,kyoto")
+	if err != nil || prefectureKey(prefs) != "27,26" {
+		t.Errorf("expected duplicates to be removed, got %q, %v", prefectureKey(prefs), err)
+	}
+
+	if _, err := parsePrefectures("13,unknown"); err == nil {
+		t.Error("expected error for unknown prefecture")
+	}
+}
+
+func TestPrefecture_MentionedIn(t *testing.T) {
+	kyoto, _ := ParsePrefecture("京都")
+	tests := []struct {
+		pref     Prefecture
+		text     string
+		expected bool
+	}{
+		{tokyo, "東京都新宿区歌舞伎町1-20-1", true},
+		{tokyo, "京都府京都市下京区", false},
+		{kyoto, "京都府京都市下京区", true},
+		{kyoto, "京都駅前店", true},
+		{kyoto, "東京都千代田区外神田1-11-5", false},
+		{kyoto, "東京都内の店舗 / 京都四条店", true},
+	}
+	for _, tt := range tests {
+		if got := tt.pref.mentionedIn(tt.text); got != tt.expected {
+			t.Errorf("%s.mentionedIn(%q) = %v, expected %v", tt.pref.Name, tt.text, got, tt.expected)
+		}
+	}
+}
+
+func TestPrefecture_LocatedIn(t *testing.T) {
+	kyoto, _ := ParsePrefecture("京都")
+	ishikawa, _ := ParsePrefecture("石川")
+	osaka, _ := ParsePrefecture("大阪")
+	kanagawa, _ := ParsePrefecture("神奈川")
+	tests := []struct {
+		pref     Prefecture
+		address  string
+		text     string
+		expected bool
+	}{
+		{tokyo, "東京都新宿区歌舞伎町1-20-1", "", true},
+		{ishikawa, "横浜市中区石川町1-1-1", "横浜石川町店 横浜市中区石川町1-1-1", false},
+		{kanagawa, "神奈川県横浜市中区石川町1-1-1", "", true},
+		{kyoto, "東京都港区京都ビル1F", "", false},
+		{kyoto, "〒600-8216 京都府京都市下京区東塩小路町1", "", true},
+		{osaka, "大阪市北区芝田1-1-1", "", true},
+		{kyoto, "大阪市北区芝田1-1-1", "京都からも近い梅田店", false},
+		// 住所がなければ項目のテキストで判定する
+		{kyoto, "", "京都四条店", true},
+		{kyoto, "", "東京都内の店舗", false},
+	}
+	for _, tt := range tests {
+		if got := tt.pref.locatedIn(tt.address, tt.text); got != tt.expected {
+			t.Errorf("%s.locatedIn(%q, %q) = %v, expected %v", tt.pref.Name, tt.address, tt.text, got, tt.expected)
+		}
+	}
+}
+
+func TestScraper_ScrapeAll_Prefectures(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		switch r.URL.Path {
+		case "/shop/tokyo/":
+			w.Write([]byte(`<div class="shop-list-item"><h3 class="shop-name">新宿西口店</h3><div class="shop-address">東京都新宿区西新宿1-12-9</div></div>`))
+		case "/shop/osaka/":
+			w.Write([]byte(`<div class="shop-list-item"><h3 class="shop-name">梅田店</h3><div class="shop-address">大阪府大阪市北区芝田1-1-1</div></div>`))
+		default:
+			// 店舗のない県の一覧は 404
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	prefs, _ := parsePrefectures("tokyo,tottori,osaka")
+	scraper := NewScraper(WithBaseURL("kaikatsu", server.URL), WithTransport(http.DefaultTransport), WithPrefectures(prefs...))
+	scraper.sources = []Source{builtinSource(t, "kaikatsu")}
+
+	result, err := scraper.ScrapeAll(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r := result.Sources[0]
+	if r.Err != nil || r.Count != 2 || requests.Load() != 3 {
+		t.Errorf("expected 2 stores from 3 prefectures, got count=%d requests=%d err=%v", r.Count, requests.Load(), r.Err)
+	}
+
+	// すべての都道府県で失敗したら取得元の失敗になる
+	prefs, _ = parsePrefectures("tottori,shimane")
+	scraper = NewScraper(WithBaseURL("kaikatsu", server.URL), WithTransport(http.DefaultTransport), WithPrefectures(prefs...))
+	scraper.sources = []Source{builtinSource(t, "kaikatsu")}
+	if _, err := scraper.ScrapeAll(context.Background()); err == nil {
+		t.Error("expected error when every prefecture fails")
+	}
+}
+
+func TestScraper_ScrapeJiqoo_PrefectureCode(t *testing.T) {
+	var query atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query.Store(r.URL.RawQuery)
+		w.Write([]byte(`<div class="shop-item"><h3 class="shop-name">札幌駅前店</h3></div>`))
+	}))
+	defer server.Close()
+
+	hokkaido, _ := ParsePrefecture("01")
+	scraper := NewScraper(WithBaseURL("jiqoo", server.URL), WithTransport(http.DefaultTransport), WithPrefectures(hokkaido))
+	if _, err := builtinSource(t, "jiqoo").Scrape(context.Background(), scraper); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if q := query.Load(); q != "pref=1" {
+		t.Errorf("expected pref=1, got %v", q)
+	}
+}
+
+func TestScraper_ScrapeManboo_Prefectures(t *testing.T) {
+	htmlContent := `
+	<ul>
+		<li class="shop-list-item"><strong>渋谷宮益坂店</strong><div class="address">東京都渋谷区渋谷1-12-1</div></li>
+		<li class="shop-list-item"><strong>京都四条河原町店</strong><div class="address">京都府京都市下京区1-1</div></li>
+		<li class="shop-list-item"><strong>横浜石川町店</strong><div class="address">神奈川県横浜市中区石川町1-1</div></li>
+	</ul>`
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kyoto, _ := ParsePrefecture("kyoto")
+	manboo := builtinSource(t, "manboo")
+	cafes := manboo.parsePage(doc, "https://www.manboo.co.jp", []Prefecture{kyoto})
+	if len(cafes) != 1 || cafes[0].Name != "京都四条河原町店" {
+		t.Errorf("expected only the Kyoto store, got %+v", cafes)
+	}
+
+	cafes = manboo.parsePage(doc, "https://www.manboo.co.jp", []Prefecture{tokyo, kyoto})
+	if len(cafes) != 2 {
+		t.Errorf("expected both stores, got %+v", cafes)
+	}
+
+	// 住所の途中の「石川」は石川県の店舗とみなさない
+	ishikawa, _ := ParsePrefecture("ishikawa")
+	if cafes := manboo.parsePage(doc, "https://www.manboo.co.jp", []Prefecture{ishikawa}); len(cafes) != 0 {
+		t.Errorf("expected no Ishikawa stores, got %+v", cafes)
+	}
+}
+
+func TestSnapshotCache_PrefectureMismatch(t *testing.T) {
+	var calls atomic.Int32
+	var noErr error
+	src := countingSource("a", &calls, []NetCafe{{Name: "A店"}}, &noErr)
+
+	cache, _ := newTestCache(t, time.Hour)
+	scraper := NewScraper()
+	scraper.sources = []Source{src}
+	if _, err := cache.ScrapeAll(context.Background(), scraper, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	osaka, _ := ParsePrefecture("osaka")
+	other := NewScraper(WithPrefectures(osaka))
+	other.sources = []Source{src}
+	if _, err := cache.ScrapeAll(context.Background(), other, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("cache for other prefectures should not be used, calls=%d", calls.Load())
+	}
+}
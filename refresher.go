@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// Refresher は一定間隔で ScrapeAll を実行し、NetCafeService の店舗一覧を差し替える。
+// 取得に失敗した取得元は前回取得できた店舗をそのまま使い続ける。
+type Refresher struct {
+	service  *NetCafeService
+	scraper  *Scraper
+	interval time.Duration
+	jitter   time.Duration
+	now      func() time.Time
+
+	mu       sync.RWMutex
+	bySource map[string][]NetCafe
+	status   RefreshStatus
+}
+
+// RefreshStatus は Refresher の直近の実行状況。
+type RefreshStatus struct {
+	// LastSuccess はすべての取得元で取得に成功した最後の時刻。
+	LastSuccess time.Time `json:"last_success"`
+	LastAttempt time.Time `json:"last_attempt"`
+	LastError   string    `json:"last_error,omitempty"`
+	// SourceSuccess は取得元ごとに最後に取得に成功した時刻。
+	SourceSuccess map[string]time.Time `json:"source_success,omitempty"`
+}
+
+// NewRefresher は interval ごと（0〜jitter のランダムな遅延を加える）に scraper で再取得し、
+// service の店舗一覧を更新する Refresher を返す。
+func NewRefresher(service *NetCafeService, scraper *Scraper, interval, jitter time.Duration) *Refresher {
+	return &Refresher{
+		service:  service,
+		scraper:  scraper,
+		interval: interval,
+		jitter:   jitter,
+		now:      time.Now,
+		bySource: make(map[string][]NetCafe),
+		status:   RefreshStatus{SourceSuccess: make(map[string]time.Time)},
+	}
+}
+
+// Run は ctx がキャンセルされるまで定期的に Refresh を呼ぶ。最初の実行は interval 経過後。
+func (r *Refresher) Run(ctx context.Context) {
+	for {
+		timer := time.NewTimer(r.nextDelay())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if err := r.Refresh(ctx); err != nil {
+			log.Printf("refresh failed: %v", err)
+		}
+	}
+}
+
+func (r *Refresher) nextDelay() time.Duration {
+	if r.jitter <= 0 {
+		return r.interval
+	}
+	return r.interval + rand.N(r.jitter)
+}
+
+// Refresh は一度だけ再取得して店舗一覧を差し替える。すべての取得元が失敗した場合は
+// 店舗一覧を変更せずにエラーを返す。
+func (r *Refresher) Refresh(ctx context.Context) error {
+	result, err := r.scraper.ScrapeAll(ctx)
+	now := r.now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.status.LastAttempt = now
+	r.status.LastError = ""
+	if err != nil {
+		r.status.LastError = err.Error()
+	}
+
+	var partial *PartialScrapeError
+	if err != nil && (!errors.As(err, &partial) || partial.AllFailed()) {
+		return err
+	}
+
+	var stores []NetCafe
+	for _, sr := range result.Sources {
+		if sr.OK() {
+			r.bySource[sr.ChainID] = sr.Stores
+			r.status.SourceSuccess[sr.ChainID] = now
+		}
+		stores = append(stores, r.bySource[sr.ChainID]...)
+	}
+	r.service.SetStores(mergeStores(stores, r.scraper.sourceOrder()))
+
+	if err == nil {
+		r.status.LastSuccess = now
+	}
+	return err
+}
+
+// Status は直近の実行状況を返す。
+func (r *Refresher) Status() RefreshStatus {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	status := r.status
+	status.SourceSuccess = make(map[string]time.Time, len(r.status.SourceSuccess))
+	for id, t := range r.status.SourceSuccess {
+		status.SourceSuccess[id] = t
+	}
+	return status
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// toggleSource は呼び出しごとに cafes/err を差し替えられる取得元。
+type toggleSource struct {
+	chainID string
+	mu      *sync.Mutex
+	cafes   *[]NetCafe
+	err     *error
+}
+
+func newToggleSource(chainID string, cafes ...NetCafe) toggleSource {
+	var err error
+	return toggleSource{chainID: chainID, mu: &sync.Mutex{}, cafes: &cafes, err: &err}
+}
+
+func (s toggleSource) Name() string    { return s.chainID }
+func (s toggleSource) ChainID() string { return s.chainID }
+func (s toggleSource) BaseURL() string { return "http://example.test" }
+
+func (s toggleSource) Scrape(ctx context.Context, _ *Scraper) ([]NetCafe, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if *s.err != nil {
+		return nil, *s.err
+	}
+	return append([]NetCafe(nil), *s.cafes...), nil
+}
+
+func (s toggleSource) set(err error, cafes ...NetCafe) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	*s.cafes = cafes
+	*s.err = err
+}
+
+func storeNames(cafes []NetCafe) []string {
+	var names []string
+	for _, c := range cafes {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+func TestRefresher_Refresh(t *testing.T) {
+	a := newToggleSource("a", NetCafe{Name: "A1店"})
+	b := newToggleSource("b", NetCafe{Name: "B1店"})
+	scraper := NewScraper()
+	scraper.sources = []Source{a, b}
+
+	service := NewNetCafeService()
+	refresher := NewRefresher(service, scraper, time.Hour, 0)
+	clock := jstTime(2026, 10, 20, 12, 0)
+	refresher.now = func() time.Time { return clock }
+
+	// 初回はすべて成功
+	if err := refresher.Refresh(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := storeNames(service.GetAll()); len(got) != 2 || got[0] != "A1店" || got[1] != "B1店" {
+		t.Errorf("unexpected stores after first refresh: %v", got)
+	}
+	if status := refresher.Status(); !status.LastSuccess.Equal(clock) {
+		t.Errorf("expected last success %v, got %v", clock, status.LastSuccess)
+	}
+
+	// bだけ失敗した場合はbの前回分を残す
+	firstSuccess := clock
+	clock = clock.Add(time.Hour)
+	a.set(nil, NetCafe{Name: "A2店"})
+	b.set(errors.New("boom"))
+	if err := refresher.Refresh(context.Background()); err == nil {
+		t.Error("expected partial failure error")
+	}
+	if got := storeNames(service.GetAll()); len(got) != 2 || got[0] != "A2店" || got[1] != "B1店" {
+		t.Errorf("unexpected stores after partial failure: %v", got)
+	}
+	status := refresher.Status()
+	if !status.LastSuccess.Equal(firstSuccess) {
+		t.Errorf("last success should not change on partial failure, got %v", status.LastSuccess)
+	}
+	if !status.SourceSuccess["a"].Equal(clock) || !status.SourceSuccess["b"].Equal(firstSuccess) {
+		t.Errorf("unexpected per-source success times: %v", status.SourceSuccess)
+	}
+	if !status.LastAttempt.Equal(clock) || status.LastError == "" {
+		t.Errorf("unexpected attempt status: %+v", status)
+	}
+
+	// すべて失敗した場合は一覧を変えない
+	a.set(errors.New("down"))
+	if err := refresher.Refresh(context.Background()); err == nil {
+		t.Error("expected error when all sources fail")
+	}
+	if got := storeNames(service.GetAll()); len(got) != 2 || got[0] != "A2店" {
+		t.Errorf("stores should be kept when all sources fail: %v", got)
+	}
+}
+
+func TestRefresher_Run(t *testing.T) {
+	var calls atomic.Int32
+	scraper := NewScraper()
+	scraper.sources = []Source{fakeSource{chainID: "a", scrape: func(ctx context.Context) ([]NetCafe, error) {
+		calls.Add(1)
+		return []NetCafe{{Name: "A店"}}, nil
+	}}}
+
+	service := NewNetCafeService()
+	refresher := NewRefresher(service, scraper, 5*time.Millisecond, 5*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		refresher.Run(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for calls.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	if calls.Load() < 2 {
+		t.Errorf("expected at least 2 refreshes, got %d", calls.Load())
+	}
+	if got := storeNames(service.GetAll()); len(got) != 1 || got[0] != "A店" {
+		t.Errorf("unexpected stores: %v", got)
+	}
+}
+
+func TestNetCafeService_ConcurrentSetStores(t *testing.T) {
+	service := NewNetCafeService()
+	samples := getSampleStores()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				service.SetStores(samples[:j%len(samples)+1])
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				service.SearchByName("新宿")
+				_ = service.GetAll()
+			}
+		}()
+	}
+	wg.Wait()
+}
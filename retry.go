@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy は一時的な失敗を再試行するときの設定。
+type RetryPolicy struct {
+	// MaxAttempts は最初の1回を含む試行回数の上限。
+	MaxAttempts int
+	BaseDelay   time.Duration
+	// MaxDelay は待ち時間の上限。Retry-After がこれより長ければ再試行しない。
+	MaxDelay time.Duration
+}
+
+var defaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    30 * time.Second,
+}
+
+// backoff は attempt 回目（1始まり）の失敗のあとに待つ時間を返す。指数的に延ばし、
+// ほかのクライアントと再試行の時刻がそろわないよう後半の半分をランダムにする。
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.BaseDelay << (attempt - 1)
+	if d <= 0 || d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	half := d / 2
+	if half <= 0 {
+		return d
+	}
+	return half + rand.N(half+1)
+}
+
+// RetryTransport は冪等なリクエストを、通信エラーや 408・429・5xx のときに再試行する RoundTripper。
+// 再試行の待ち時間がリクエストの時間の上限に含まれないよう、上限は下の層の TimeoutTransport で試行ごとに設ける。
+type RetryTransport struct {
+	next   http.RoundTripper
+	policy RetryPolicy
+	wait   func(ctx context.Context, d time.Duration) error
+}
+
+// NewRetryTransport は policy に従って再試行する RetryTransport を返す。next が nil なら
+// http.DefaultTransport を使う。
+func NewRetryTransport(next http.RoundTripper, policy RetryPolicy) *RetryTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+	return &RetryTransport{next: next, policy: policy, wait: sleepContext}
+}
+
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	counter := fetchCounterFrom(req.Context())
+	retryable := idempotent(req)
+
+	for attempt := 1; ; attempt++ {
+		if counter != nil {
+			counter.attempts.Add(1)
+			if attempt > 1 {
+				counter.retries.Add(1)
+			}
+		}
+
+		resp, err := t.next.RoundTrip(req)
+		if !retryable || attempt >= t.policy.MaxAttempts || req.Context().Err() != nil {
+			return resp, err
+		}
+
+		var delay time.Duration
+		var reason string
+		switch {
+		case err != nil:
+			if !transientError(err) {
+				return nil, err
+			}
+			delay, reason = t.policy.backoff(attempt), err.Error()
+		case retryableStatus(resp.StatusCode):
+			delay = t.policy.backoff(attempt)
+			if after, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+				if after > t.policy.MaxDelay {
+					return resp, nil
+				}
+				delay = max(delay, after)
+			}
+			reason = resp.Status
+		default:
+			return resp, nil
+		}
+
+		// 待っている間に呼び出し側の期限が来るなら、context のエラーではなく最後の結果を返す
+		if deadline, ok := req.Context().Deadline(); ok && time.Until(deadline) < delay {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+
+		log.Printf("retrying %s %s in %v (attempt %d/%d): %s",
+			req.Method, req.URL, delay.Round(time.Millisecond), attempt+1, t.policy.MaxAttempts, reason)
+		if err := t.wait(req.Context(), delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// idempotent は本文のない冪等なリクエストかどうかを返す。
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return req.Body == nil || req.Body == http.NoBody
+	}
+	return false
+}
+
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests,
+		http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// transientError は再試行すれば成功しうる通信エラーかどうかを返す。
+// 名前解決の失敗（ホストが存在しない）や robots.txt による禁止は再試行しない。
+func transientError(err error) bool {
+	if errors.Is(err, ErrDisallowedByRobots) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTimeout || dnsErr.IsTemporary
+	}
+	var netErr net.Error
+	return errors.Is(err, ErrRequestTimeout) ||
+		errors.As(err, &netErr) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// retryAfter は Retry-After ヘッダー（秒数または HTTP 日付）を待ち時間にする。
+func retryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(v); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0), true
+	}
+	return 0, false
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// fetchCounter は context を通じて、取得元ひとつ分の HTTP の試行回数（RetryTransport が数える）と
+// 一覧のページ数（fetchPages が数える）を ScrapeAll に伝える。
+type fetchCounter struct {
+	attempts atomic.Int64
+	retries  atomic.Int64
+	pages    atomic.Int64
+}
+
+type fetchCounterKey struct{}
+
+func withFetchCounter(ctx context.Context) (context.Context, *fetchCounter) {
+	c := &fetchCounter{}
+	return context.WithValue(ctx, fetchCounterKey{}, c), c
+}
+
+func fetchCounterFrom(ctx context.Context) *fetchCounter {
+	c, _ := ctx.Value(fetchCounterKey{}).(*fetchCounter)
+	return c
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestRetryTransport は待たずに待ち時間だけを記録する RetryTransport を返す。
+func newTestRetryTransport(next http.RoundTripper, maxAttempts int) (*RetryTransport, *[]time.Duration) {
+	var delays []time.Duration
+	policy := RetryPolicy{MaxAttempts: maxAttempts, BaseDelay: 100 * time.Millisecond, MaxDelay: 5 * time.Second}
+	transport := NewRetryTransport(next, policy)
+	transport.wait = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return ctx.Err()
+	}
+	return transport, &delays
+}
+
+// flakyServer は最初の failures 回だけ status を返し、その後は body を返す。
+func flakyServer(t *testing.T, failures int, status int, header http.Header, body string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if int(requests.Add(1)) <= failures {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(status)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestRetryTransport_RetryableStatus(t *testing.T) {
+	server, requests := flakyServer(t, 2, http.StatusServiceUnavailable, nil, "ok")
+	transport, delays := newTestRetryTransport(nil, 3)
+
+	ctx, counter := withFetchCounter(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	resp, err := (&http.Client{Transport: transport}).Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || requests.Load() != 3 {
+		t.Errorf("expected success on third attempt, status=%d requests=%d", resp.StatusCode, requests.Load())
+	}
+	if counter.attempts.Load() != 3 || counter.retries.Load() != 2 {
+		t.Errorf("unexpected counter: attempts=%d retries=%d", counter.attempts.Load(), counter.retries.Load())
+	}
+	// 指数的に延び、ジッターで後半の半分に収まる
+	if len(*delays) != 2 {
+		t.Fatalf("expected 2 waits, got %v", *delays)
+	}
+	for i, d := range *delays {
+		base := 100 * time.Millisecond << i
+		if d < base/2 || d > base {
+			t.Errorf("delay %d = %v, expected between %v and %v", i, d, base/2, base)
+		}
+	}
+}
+
+func TestRetryTransport_GiveUp(t *testing.T) {
+	server, requests := flakyServer(t, 10, http.StatusBadGateway, nil, "ok")
+	transport, _ := newTestRetryTransport(nil, 3)
+
+	resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadGateway || requests.Load() != 3 {
+		t.Errorf("expected last 502 after 3 attempts, status=%d requests=%d", resp.StatusCode, requests.Load())
+	}
+}
+
+func TestRetryTransport_RetryAfter(t *testing.T) {
+	t.Run("honored", func(t *testing.T) {
+		server, requests := flakyServer(t, 1, http.StatusTooManyRequests, http.Header{"Retry-After": {"2"}}, "ok")
+		transport, delays := newTestRetryTransport(nil, 3)
+
+		resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp.Body.Close()
+		if requests.Load() != 2 || len(*delays) != 1 || (*delays)[0] != 2*time.Second {
+			t.Errorf("expected to wait Retry-After, requests=%d delays=%v", requests.Load(), *delays)
+		}
+	})
+
+	t.Run("longer than max delay", func(t *testing.T) {
+		server, requests := flakyServer(t, 1, http.StatusServiceUnavailable, http.Header{"Retry-After": {"3600"}}, "ok")
+		transport, _ := newTestRetryTransport(nil, 3)
+
+		resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusServiceUnavailable || requests.Load() != 1 {
+			t.Errorf("expected no retry, status=%d requests=%d", resp.StatusCode, requests.Load())
+		}
+	})
+}
+
+func TestRetryTransport_NotRetried(t *testing.T) {
+	t.Run("client error", func(t *testing.T) {
+		server, requests := flakyServer(t, 1, http.StatusNotFound, nil, "ok")
+		transport, _ := newTestRetryTransport(nil, 3)
+		resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound || requests.Load() != 1 {
+			t.Errorf("expected no retry for 404, requests=%d", requests.Load())
+		}
+	})
+
+	t.Run("POST", func(t *testing.T) {
+		server, requests := flakyServer(t, 1, http.StatusServiceUnavailable, nil, "ok")
+		transport, _ := newTestRetryTransport(nil, 3)
+		resp, err := (&http.Client{Transport: transport}).Post(server.URL, "text/plain", strings.NewReader("x"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp.Body.Close()
+		if requests.Load() != 1 {
+			t.Errorf("expected no retry for POST, requests=%d", requests.Load())
+		}
+	})
+
+	t.Run("host not found", func(t *testing.T) {
+		var calls int
+		next := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			calls++
+			return nil, &net.DNSError{Err: "no such host", Name: req.URL.Host, IsNotFound: true}
+		})
+		transport, _ := newTestRetryTransport(next, 3)
+		if _, err := (&http.Client{Transport: transport}).Get("http://shop.invalid/"); err == nil {
+			t.Error("expected error")
+		}
+		if calls != 1 {
+			t.Errorf("expected no retry for DNS not found, calls=%d", calls)
+		}
+	})
+
+	t.Run("robots.txt", func(t *testing.T) {
+		var calls int
+		next := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			calls++
+			return nil, ErrDisallowedByRobots
+		})
+		transport, _ := newTestRetryTransport(next, 3)
+		if _, err := (&http.Client{Transport: transport}).Get("http://example.test/private/"); !errors.Is(err, ErrDisallowedByRobots) {
+			t.Errorf("expected ErrDisallowedByRobots, got %v", err)
+		}
+		if calls != 1 {
+			t.Errorf("expected no retry, calls=%d", calls)
+		}
+	})
+}
+
+func TestRetryTransport_ConnectionRefused(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	var calls int
+	next := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return http.DefaultTransport.RoundTrip(req)
+	})
+	transport, delays := newTestRetryTransport(next, 3)
+	if _, err := (&http.Client{Transport: transport}).Get(url); err == nil {
+		t.Error("expected error")
+	}
+	if calls != 3 || len(*delays) != 2 {
+		t.Errorf("expected 3 attempts, calls=%d delays=%v", calls, *delays)
+	}
+}
+
+func TestRetryTransport_CanceledWhileWaiting(t *testing.T) {
+	server, requests := flakyServer(t, 10, http.StatusServiceUnavailable, nil, "ok")
+	transport := NewRetryTransport(nil, RetryPolicy{MaxAttempts: 5, BaseDelay: time.Hour, MaxDelay: time.Hour})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	if _, err := (&http.Client{Transport: transport}).Do(req); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if requests.Load() != 1 {
+		t.Errorf("expected a single request, got %d", requests.Load())
+	}
+}
+
+func TestRetryTransport_DeadlineBeforeRetry(t *testing.T) {
+	server, requests := flakyServer(t, 10, http.StatusServiceUnavailable, http.Header{"Retry-After": {"10"}}, "ok")
+	transport := NewRetryTransport(nil, defaultRetryPolicy)
+
+	// 期限までに Retry-After を待てなければ、context のエラーではなく最後の応答を返す
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	resp, err := (&http.Client{Transport: transport}).Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || requests.Load() != 1 {
+		t.Errorf("expected the last 503 without waiting, status=%d requests=%d", resp.StatusCode, requests.Load())
+	}
+}
+
+func TestRetryTransport_RetryAfterLongerThanRequestTimeout(t *testing.T) {
+	server, requests := flakyServer(t, 1, http.StatusTooManyRequests, http.Header{"Retry-After": {"1"}}, "ok")
+	// Retry-After（1秒）はリクエスト1回の上限（200ms）より長いが、待ち時間は上限に含めない
+	client := &http.Client{Transport: NewRetryTransport(NewTimeoutTransport(nil, 200*time.Millisecond), defaultRetryPolicy)}
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || requests.Load() != 2 {
+		t.Errorf("expected Retry-After to be honored, status=%d requests=%d", resp.StatusCode, requests.Load())
+	}
+}
+
+func TestRetryTransport_RequestTimeoutRetried(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	transport, _ := newTestRetryTransport(NewTimeoutTransport(nil, 50*time.Millisecond), 3)
+
+	resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if requests.Load() != 2 {
+		t.Errorf("expected a timed-out attempt to be retried, got %d requests", requests.Load())
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected time.Duration
+		ok       bool
+	}{
+		{"120", 2 * time.Minute, true},
+		{"0", 0, true},
+		{"Mon, 01 Jan 2001 00:00:00 GMT", 0, true}, // 過去の日時は待たない
+		{"", 0, false},
+		{"soon", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := retryAfter(tt.value)
+		if got != tt.expected || ok != tt.ok {
+			t.Errorf("retryAfter(%q) = %v, %v; expected %v, %v", tt.value, got, ok, tt.expected, tt.ok)
+		}
+	}
+}
+
+func TestScraper_ScrapeAll_Attempts(t *testing.T) {
+	htmlContent := `<html><body><div class="shop-item"><h3 class="shop-name">池袋西口ROSA店</h3></div></body></html>`
+	server, _ := flakyServer(t, 1, http.StatusServiceUnavailable, nil, htmlContent)
+
+	transport, _ := newTestRetryTransport(nil, 3)
+	scraper := NewScraper(WithBaseURL("jiqoo", server.URL), WithTransport(transport))
+	scraper.sources = []Source{builtinSource(t, "jiqoo")}
+
+	result, err := scraper.ScrapeAll(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r := result.Sources[0]
+	if r.Count != 1 || r.Attempts != 2 || r.Retries != 1 {
+		t.Errorf("unexpected result: count=%d attempts=%d retries=%d", r.Count, r.Attempts, r.Retries)
+	}
+}
+
+func TestNetCafeService_FetchFromAPI_Retry(t *testing.T) {
+	server, requests := flakyServer(t, 1, http.StatusBadGateway, nil, `{"name": "テスト店"}`)
+
+	service := NewNetCafeService()
+	transport, _ := newTestRetryTransport(nil, 3)
+	service.client.Transport = transport
+
+	cafe, err := service.FetchFromAPI(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cafe.Name != "テスト店" || requests.Load() != 2 {
+		t.Errorf("expected retry to succeed, name=%q requests=%d", cafe.Name, requests.Load())
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
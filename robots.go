@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// robotsRules は robots.txt のうち、このクローラーに適用されるグループの規則。
+type robotsRules struct {
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+type robotsRule struct {
+	allow   bool
+	pattern string
+	re      *regexp.Regexp
+}
+
+// parseRobots は robots.txt を解析し、userAgent に一致するグループ（なければ "*"）の規則を返す。
+// userAgent は "netcafe-go/1.0 (...)" のような User-Agent ヘッダーでよく、先頭の製品名で照合する。
+func parseRobots(r io.Reader, userAgent string) *robotsRules {
+	token := strings.ToLower(userAgent)
+	if i := strings.IndexAny(token, "/ "); i >= 0 {
+		token = token[:i]
+	}
+
+	type group struct {
+		agents []string
+		rules  robotsRules
+	}
+	var groups []*group
+	var current *group
+	inAgents := false
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// 連続する User-agent 行はひとつのグループにまとめる
+			if !inAgents {
+				current = &group{}
+				groups = append(groups, current)
+				inAgents = true
+			}
+			// 空の User-agent はどのクローラーにも一致させない（空文字列はどの UA にも含まれるため）
+			if value != "" {
+				current.agents = append(current.agents, strings.ToLower(value))
+			}
+			continue
+		case "allow", "disallow":
+			if current != nil && value != "" {
+				current.rules.rules = append(current.rules.rules, robotsRule{allow: key == "allow", pattern: value, re: robotsPattern(value)})
+			}
+		case "crawl-delay":
+			if current != nil {
+				if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+					current.rules.crawlDelay = time.Duration(seconds * float64(time.Second))
+				}
+			}
+		}
+		inAgents = false
+	}
+
+	var wildcard *robotsRules
+	for _, g := range groups {
+		for _, agent := range g.agents {
+			if agent == "*" {
+				if wildcard == nil {
+					wildcard = &g.rules
+				}
+				continue
+			}
+			if token != "" && strings.Contains(token, agent) {
+				return &g.rules
+			}
+		}
+	}
+	if wildcard != nil {
+		return wildcard
+	}
+	return &robotsRules{}
+}
+
+// Allowed は path（クエリ文字列を含めてよい）を取得してよいかどうかを返す。
+// 最も長く一致した規則に従い、同じ長さなら Allow を優先する。
+func (r *robotsRules) Allowed(path string) bool {
+	if path == "/robots.txt" {
+		return true
+	}
+	matched := -1
+	allowed := true
+	for _, rule := range r.rules {
+		if !rule.re.MatchString(path) {
+			continue
+		}
+		n := len(rule.pattern)
+		if n > matched || (n == matched && rule.allow) {
+			matched = n
+			allowed = rule.allow
+		}
+	}
+	return allowed
+}
+
+// robotsPattern は "*"（任意の文字列）と末尾の "$"（パスの終端）を解釈する前方一致の正規表現にする。
+func robotsPattern(pattern string) *regexp.Regexp {
+	anchored := strings.HasSuffix(pattern, "$")
+	expr := strings.ReplaceAll(regexp.QuoteMeta(strings.TrimSuffix(pattern, "$")), `\*`, ".*")
+	if anchored {
+		expr += "$"
+	}
+	return regexp.MustCompile("^" + expr)
+}
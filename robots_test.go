@@ -0,0 +1,85 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRobots(t *testing.T) {
+	robotsTxt := `
+# サイト全体の設定
+User-agent: *
+Disallow: /private/
+Allow: /private/shop/
+Disallow: /*.pdf$
+Disallow: /search?
+
+User-agent: BadBot
+User-agent: netcafe-go
+Disallow: /shop/detail/
+Crawl-delay: 2.5
+`
+
+	t.Run("matching group", func(t *testing.T) {
+		rules := parseRobots(strings.NewReader(robotsTxt), defaultUserAgent)
+		if rules.crawlDelay != 2500*time.Millisecond {
+			t.Errorf("expected crawl delay 2.5s, got %v", rules.crawlDelay)
+		}
+		tests := map[string]bool{
+			"/shop/":          true,
+			"/shop/detail/12": false,
+			"/private/":       true, // 自分向けのグループがあれば "*" は使わない
+			"/robots.txt":     true,
+		}
+		for path, expected := range tests {
+			if got := rules.Allowed(path); got != expected {
+				t.Errorf("Allowed(%q) = %v, expected %v", path, got, expected)
+			}
+		}
+	})
+
+	t.Run("wildcard group", func(t *testing.T) {
+		rules := parseRobots(strings.NewReader(robotsTxt), "OtherBot/2.0")
+		if rules.crawlDelay != 0 {
+			t.Errorf("expected no crawl delay, got %v", rules.crawlDelay)
+		}
+		tests := map[string]bool{
+			"/":                   true,
+			"/private/":           false,
+			"/private/data":       false,
+			"/private/shop/":      true, // より長い Allow が優先
+			"/files/menu.pdf":     false,
+			"/files/menu.pdf?v=1": true, // "$" はパスの終端
+			"/search?q=新宿":        false,
+			"/search":             true,
+		}
+		for path, expected := range tests {
+			if got := rules.Allowed(path); got != expected {
+				t.Errorf("Allowed(%q) = %v, expected %v", path, got, expected)
+			}
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		rules := parseRobots(strings.NewReader(""), defaultUserAgent)
+		if !rules.Allowed("/anything") {
+			t.Error("expected everything to be allowed")
+		}
+	})
+
+	t.Run("empty disallow", func(t *testing.T) {
+		rules := parseRobots(strings.NewReader("User-agent: *\nDisallow:\n"), defaultUserAgent)
+		if !rules.Allowed("/shop/") {
+			t.Error("empty Disallow should allow everything")
+		}
+	})
+
+	t.Run("empty user-agent", func(t *testing.T) {
+		robotsTxt := "User-agent:\nDisallow: /\n\nUser-agent: *\nDisallow: /private/\n"
+		rules := parseRobots(strings.NewReader(robotsTxt), defaultUserAgent)
+		if !rules.Allowed("/shop/") || rules.Allowed("/private/") {
+			t.Error("an empty User-agent should not match any crawler")
+		}
+	})
+}
@@ -1,192 +1,145 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
-	"regexp"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
 )
 
-type Scraper struct {
-	client *http.Client
+// Source は店舗一覧を取得・解析するチェーンごとの取得元。
+// RegisterSource で登録すると ScrapeAll の対象になる。
+type Source interface {
+	Name() string
+	ChainID() string
+	// BaseURL は既定の接続先（スキームとホスト）。WithBaseURL で差し替えられる。
+	BaseURL() string
+	Scrape(ctx context.Context, s *Scraper) ([]NetCafe, error)
 }
 
-func NewScraper() *Scraper {
-	return &Scraper{
-		client: &http.Client{
-			Timeout: 15 * time.Second,
-		},
+// sourceRegistry は登録済みの取得元。組み込みの取得元は sources.yaml の定義から登録する（spec.go）。
+var sourceRegistry []Source
+
+// RegisterSource は取得元を登録する。同じ ChainID を二重に登録すると panic する。
+func RegisterSource(src Source) {
+	if src == nil {
+		panic("netcafe: RegisterSource source is nil")
+	}
+	for _, registered := range sourceRegistry {
+		if registered.ChainID() == src.ChainID() {
+			panic("netcafe: RegisterSource called twice for " + src.ChainID())
+		}
 	}
+	sourceRegistry = append(sourceRegistry, src)
 }
 
-func (s *Scraper) ScrapeKaikatsuClub() ([]NetCafe, error) {
-	url := "https://www.kaikatsu.jp/shop/tokyo/"
-	
-	resp, err := s.client.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch page: %w", err)
-	}
-	defer resp.Body.Close()
+// RegisteredSources は登録順に取得元を返す。
+func RegisteredSources() []Source {
+	sources := make([]Source, len(sourceRegistry))
+	copy(sources, sourceRegistry)
+	return sources
+}
 
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("status code error: %d %s", resp.StatusCode, resp.Status)
-	}
+const defaultConcurrency = 4
 
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+type Scraper struct {
+	client      *http.Client
+	sources     []Source
+	baseURLs    map[string]string
+	concurrency int
+	// detailConcurrency が0より大きければ詳細ページも取得する（WithDetails）。
+	detailConcurrency int
+	maxPages          int
+	prefectures       []Prefecture
+	// rate はホストごとの1秒あたりのリクエスト数の上限。0なら不明（制限なし）として扱う。
+	rate float64
+}
+
+type ScraperOption func(*Scraper)
+
+// WithBaseURL は chainID の取得元の接続先を baseURL に差し替える。
+// モックサーバーやステージング環境に向けるときに使う。
+func WithBaseURL(chainID, baseURL string) ScraperOption {
+	return func(s *Scraper) {
+		s.baseURLs[chainID] = strings.TrimSuffix(baseURL, "/")
 	}
+}
 
-	var cafes []NetCafe
-	
-	doc.Find(".shop-list-item").Each(func(i int, s *goquery.Selection) {
-		name := strings.TrimSpace(s.Find(".shop-name").Text())
-		if name == "" {
-			name = strings.TrimSpace(s.Find("h3").Text())
-		}
-		
-		address := strings.TrimSpace(s.Find(".shop-address").Text())
-		if address == "" {
-			address = strings.TrimSpace(s.Find(".address").Text())
-		}
-		
-		hours := "24時間営業"
-		if hoursText := s.Find(".shop-hours").Text(); hoursText != "" {
-			hours = strings.TrimSpace(hoursText)
-		}
-		
-		phone := strings.TrimSpace(s.Find(".shop-tel").Text())
-		if phone == "" {
-			phone = strings.TrimSpace(s.Find(".tel").Text())
+// WithConcurrency は ScrapeAll で同時に取得する取得元の上限を設定する。
+func WithConcurrency(n int) ScraperOption {
+	return func(s *Scraper) {
+		if n > 0 {
+			s.concurrency = n
 		}
-		
-		shopURL, _ := s.Find("a").Attr("href")
-		if !strings.HasPrefix(shopURL, "http") && shopURL != "" {
-			shopURL = "https://www.kaikatsu.jp" + shopURL
-		}
-		
-		if name != "" {
-			cafes = append(cafes, NetCafe{
-				Name:     "快活CLUB " + name,
-				Location: address,
-				Hours:    hours,
-				Phone:    phone,
-				URL:      shopURL,
-			})
-		}
-	})
+	}
+}
 
-	if len(cafes) == 0 {
-		cafes = s.scrapeKaikatsuAlternative(doc)
+// WithRateLimit はホストごとに毎秒 rate 回までにリクエストを絞る。WithTransport を指定した場合は
+// その RoundTripper のレート制限を伝えるだけで、詳細ページの同時取得数をこれに合わせる。
+func WithRateLimit(rate float64) ScraperOption {
+	return func(s *Scraper) {
+		if rate > 0 {
+			s.rate = rate
+		}
 	}
+}
 
-	return cafes, nil
+// WithTransport は取得に使う http.Client の RoundTripper を差し替える。
+func WithTransport(rt http.RoundTripper) ScraperOption {
+	return func(s *Scraper) {
+		s.client.Transport = rt
+	}
 }
 
-func (s *Scraper) scrapeKaikatsuAlternative(doc *goquery.Document) []NetCafe {
-	var cafes []NetCafe
-	
-	doc.Find("li").Each(func(i int, sel *goquery.Selection) {
-		text := sel.Text()
-		if strings.Contains(text, "店") || strings.Contains(text, "快活") {
-			lines := strings.Split(text, "\n")
-			name := ""
-			address := ""
-			phone := ""
-			
-			for _, line := range lines {
-				line = strings.TrimSpace(line)
-				if line == "" {
-					continue
-				}
-				
-				if strings.Contains(line, "店") && name == "" {
-					name = line
-				} else if (strings.Contains(line, "区") || strings.Contains(line, "市")) && address == "" {
-					address = line
-				} else if matched, _ := regexp.MatchString(`\d{2,4}-\d{2,4}-\d{4}`, line); matched {
-					phone = line
-				}
-			}
-			
-			if name != "" {
-				if !strings.HasPrefix(name, "快活CLUB") {
-					name = "快活CLUB " + name
-				}
-				cafes = append(cafes, NetCafe{
-					Name:     name,
-					Location: address,
-					Hours:    "24時間営業",
-					Phone:    phone,
-					URL:      "https://www.kaikatsu.jp/",
-				})
-			}
+func NewScraper(opts ...ScraperOption) *Scraper {
+	s := &Scraper{
+		client:      &http.Client{},
+		sources:     RegisteredSources(),
+		baseURLs:    make(map[string]string),
+		concurrency: defaultConcurrency,
+		maxPages:    defaultMaxPages,
+		prefectures: []Prefecture{tokyo},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.client.Transport == nil {
+		if s.rate == 0 {
+			s.rate = defaultRateLimit
 		}
-	})
-	
-	return cafes
+		// 時間の上限はリクエスト1回ごと（TimeoutTransport）。レート制限や再試行の待ち時間は含めない
+		s.client.Transport = NewRetryTransport(NewPoliteTransport(NewTimeoutTransport(nil, defaultRequestTimeout), defaultUserAgent, s.rate), defaultRetryPolicy)
+	}
+	return s
 }
 
-func (s *Scraper) ScrapeJiqoo() ([]NetCafe, error) {
-	url := "https://jiqoo.jp/shop/?pref=13"
-	
-	resp, err := s.client.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch page: %w", err)
+func (s *Scraper) baseURL(src Source) string {
+	if u, ok := s.baseURLs[src.ChainID()]; ok {
+		return u
 	}
-	defer resp.Body.Close()
+	return src.BaseURL()
+}
 
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("status code error: %d %s", resp.StatusCode, resp.Status)
+func absoluteURL(base, href string) string {
+	if !strings.HasPrefix(href, "http") && href != "" {
+		return base + href
 	}
+	return href
+}
 
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
+func (s *Scraper) fetchDocument(ctx context.Context, url string) (*goquery.Document, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	var cafes []NetCafe
-	
-	doc.Find(".shop-item, .store-item").Each(func(i int, s *goquery.Selection) {
-		name := strings.TrimSpace(s.Find(".shop-name, .store-name, h3").Text())
-		address := strings.TrimSpace(s.Find(".shop-address, .store-address, .address").Text())
-		phone := strings.TrimSpace(s.Find(".shop-tel, .store-tel, .tel").Text())
-		
-		hours := "24時間営業"
-		if hoursText := s.Find(".shop-hours, .hours").Text(); hoursText != "" {
-			hours = strings.TrimSpace(hoursText)
-		}
-		
-		shopURL, _ := s.Find("a").Attr("href")
-		if !strings.HasPrefix(shopURL, "http") && shopURL != "" {
-			shopURL = "https://jiqoo.jp" + shopURL
-		}
-		
-		if name != "" {
-			if !strings.Contains(name, "自遊空間") {
-				name = "自遊空間 " + name
-			}
-			cafes = append(cafes, NetCafe{
-				Name:     name,
-				Location: address,
-				Hours:    hours,
-				Phone:    phone,
-				URL:      shopURL,
-			})
-		}
-	})
-
-	return cafes, nil
-}
-
-func (s *Scraper) ScrapeManboo() ([]NetCafe, error) {
-	url := "https://www.manboo.co.jp/shop/"
-	
-	resp, err := s.client.Get(url)
+	resp, err := s.client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch page: %w", err)
 	}
@@ -196,103 +149,160 @@ func (s *Scraper) ScrapeManboo() ([]NetCafe, error) {
 		return nil, fmt.Errorf("status code error: %d %s", resp.StatusCode, resp.Status)
 	}
 
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	doc, err := goquery.NewDocumentFromReader(decodeHTML(resp.Body, resp.Header.Get("Content-Type")))
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse HTML: %w", err)
 	}
+	return doc, nil
+}
 
-	var cafes []NetCafe
-	
-	doc.Find(".shop-list-item, .store-item, li").Each(func(i int, s *goquery.Selection) {
-		text := s.Text()
-		if strings.Contains(text, "東京") || strings.Contains(text, "店") {
-			name := ""
-			address := ""
-			phone := ""
-			
-			nameElem := s.Find(".shop-name, h3, strong").First()
-			if nameElem.Length() > 0 {
-				name = strings.TrimSpace(nameElem.Text())
-			}
-			
-			addressElem := s.Find(".address, .shop-address").First()
-			if addressElem.Length() > 0 {
-				address = strings.TrimSpace(addressElem.Text())
-			}
-			
-			phoneElem := s.Find(".tel, .phone").First()
-			if phoneElem.Length() > 0 {
-				phone = strings.TrimSpace(phoneElem.Text())
-			}
-			
-			if name == "" && strings.Contains(text, "店") {
-				lines := strings.Split(text, "\n")
-				for _, line := range lines {
-					line = strings.TrimSpace(line)
-					if strings.Contains(line, "店") && name == "" {
-						name = line
-					}
-				}
-			}
-			
-			if name != "" && strings.Contains(text, "東京") {
-				if !strings.Contains(name, "マンボー") {
-					name = "マンボー " + name
-				}
-				cafes = append(cafes, NetCafe{
-					Name:     name,
-					Location: address,
-					Hours:    "24時間営業",
-					Phone:    phone,
-					URL:      "https://www.manboo.co.jp/",
-				})
-			}
-		}
-	})
+// SourceResult は取得元ひとつ分の取得結果。
+type SourceResult struct {
+	Source   string
+	ChainID  string
+	Stores   []NetCafe
+	Count    int
+	Duration time.Duration
+	// FetchedAt は Stores を取得した時刻。Cached ならキャッシュに保存された時刻。
+	FetchedAt time.Time
+	// Cached は Stores がキャッシュから読み込んだものかどうか。Err があれば古いキャッシュの代替。
+	Cached bool
+	// Attempts は再試行を含む HTTP リクエストの回数、Retries はそのうちの再試行の回数。
+	Attempts int
+	Retries  int
+	// Pages はたどった一覧ページの数。
+	Pages int
+	// Details は詳細ページを解析できた店舗の数。
+	Details int
+	// DetailErr は詳細ページの一部を取得できなかったときの *DetailError。Err と違い、店舗は一覧の情報で使える。
+	DetailErr error
+	Err       error
+}
 
-	return cafes, nil
+func (r SourceResult) OK() bool {
+	return r.Err == nil
 }
 
-func (s *Scraper) ScrapeAll() ([]NetCafe, error) {
-	var allCafes []NetCafe
-	var errors []string
+// ScrapeResult は ScrapeAll の結果。Sources は取得元の登録順に並ぶ。
+type ScrapeResult struct {
+	Stores  []NetCafe
+	Sources []SourceResult
+}
 
-	fmt.Println("快活CLUBの店舗情報を取得中...")
-	kaikatsu, err := s.ScrapeKaikatsuClub()
-	if err != nil {
-		errors = append(errors, fmt.Sprintf("快活CLUB: %v", err))
-		log.Printf("Error scraping Kaikatsu: %v", err)
-	} else {
-		allCafes = append(allCafes, kaikatsu...)
-		fmt.Printf("  → %d店舗を取得\n", len(kaikatsu))
-	}
+// PartialScrapeError は一部またはすべての取得元が失敗したことを表す。
+// errors.As で取り出して失敗した取得元を確認できる。
+type PartialScrapeError struct {
+	Failed []SourceResult
+	// Incomplete は取得できたが詳細ページの一部が失敗した取得元（DetailErr がある）。
+	Incomplete []SourceResult
+	Total      int
+}
 
-	fmt.Println("自遊空間の店舗情報を取得中...")
-	jiqoo, err := s.ScrapeJiqoo()
-	if err != nil {
-		errors = append(errors, fmt.Sprintf("自遊空間: %v", err))
-		log.Printf("Error scraping Jiqoo: %v", err)
-	} else {
-		allCafes = append(allCafes, jiqoo...)
-		fmt.Printf("  → %d店舗を取得\n", len(jiqoo))
+func (e *PartialScrapeError) Error() string {
+	var msgs []string
+	for _, r := range e.Failed {
+		msgs = append(msgs, fmt.Sprintf("%s: %v", r.Source, r.Err))
 	}
+	for _, r := range e.Incomplete {
+		msgs = append(msgs, fmt.Sprintf("%s: %v", r.Source, r.DetailErr))
+	}
+	if len(e.Failed) == 0 {
+		return fmt.Sprintf("%d of %d sources incomplete: %s", len(e.Incomplete), e.Total, strings.Join(msgs, "; "))
+	}
+	return fmt.Sprintf("%d of %d sources failed: %s", len(e.Failed), e.Total, strings.Join(msgs, "; "))
+}
 
-	fmt.Println("マンボーの店舗情報を取得中...")
-	manboo, err := s.ScrapeManboo()
-	if err != nil {
-		errors = append(errors, fmt.Sprintf("マンボー: %v", err))
-		log.Printf("Error scraping Manboo: %v", err)
-	} else {
-		allCafes = append(allCafes, manboo...)
-		fmt.Printf("  → %d店舗を取得\n", len(manboo))
+func (e *PartialScrapeError) Unwrap() []error {
+	var errs []error
+	for _, r := range e.Failed {
+		errs = append(errs, r.Err)
+	}
+	for _, r := range e.Incomplete {
+		errs = append(errs, r.DetailErr)
 	}
+	return errs
+}
 
-	if len(errors) > 0 {
-		fmt.Println("\n取得に失敗したサイト:")
-		for _, e := range errors {
-			fmt.Printf("  - %s\n", e)
+// AllFailed はすべての取得元が失敗したかどうかを返す。
+func (e *PartialScrapeError) AllFailed() bool {
+	return len(e.Failed) == e.Total
+}
+
+// scrapeError は results に失敗した取得元か詳細ページの一部が失敗した取得元があれば *PartialScrapeError を返す。
+func scrapeError(results []SourceResult) error {
+	e := &PartialScrapeError{Total: len(results)}
+	for _, r := range results {
+		switch {
+		case !r.OK():
+			e.Failed = append(e.Failed, r)
+		case r.DetailErr != nil:
+			e.Incomplete = append(e.Incomplete, r)
 		}
 	}
+	if len(e.Failed) == 0 && len(e.Incomplete) == 0 {
+		return nil
+	}
+	return e
+}
+
+// ScrapeAll は登録済みの取得元を並行して取得し、取得元の登録順・店舗名順に
+// 並べた結果を返す。失敗した取得元や、詳細ページの一部が取得できなかった取得元があれば
+// 結果とともに *PartialScrapeError を返す。
+// ctx がキャンセルされると未完了の取得元は ctx.Err() で失敗扱いになる。
+func (s *Scraper) ScrapeAll(ctx context.Context) (*ScrapeResult, error) {
+	results := make([]SourceResult, len(s.sources))
 
-	return allCafes, nil
-}
\ No newline at end of file
+	sem := make(chan struct{}, s.concurrency)
+	var wg sync.WaitGroup
+	for i, src := range s.sources {
+		results[i] = SourceResult{Source: src.Name(), ChainID: src.ChainID()}
+		wg.Add(1)
+		go func(r *SourceResult, src Source) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				r.Err = ctx.Err()
+				return
+			}
+			srcCtx, counter := withFetchCounter(ctx)
+			start := time.Now()
+			cafes, err := src.Scrape(srcCtx, s)
+			if parser, ok := src.(DetailParser); ok && err == nil && s.detailConcurrency > 0 {
+				r.Details, r.DetailErr = s.scrapeDetails(srcCtx, src, parser, cafes)
+			}
+			r.Duration = time.Since(start)
+			r.Attempts, r.Retries = int(counter.attempts.Load()), int(counter.retries.Load())
+			r.Pages = int(counter.pages.Load())
+			if err != nil {
+				r.Err = err
+				return
+			}
+			for i := range cafes {
+				cafes[i].setChain(sourceChain(src))
+				cafes[i].Sources = []string{src.ChainID()}
+				cafes[i].normalize()
+			}
+			cafes = mergeStores(cafes, nil)
+			sort.SliceStable(cafes, func(a, b int) bool {
+				return cafes[a].Name < cafes[b].Name
+			})
+			r.Stores = cafes
+			r.Count = len(cafes)
+			r.FetchedAt = time.Now()
+		}(&results[i], src)
+	}
+	wg.Wait()
+
+	result := &ScrapeResult{Sources: results}
+	for _, r := range results {
+		if !r.OK() {
+			log.Printf("Error scraping %s: %v", r.ChainID, r.Err)
+			continue
+		}
+		result.Stores = append(result.Stores, r.Stores...)
+	}
+	result.Stores = mergeStores(result.Stores, s.sourceOrder())
+	return result, scrapeError(results)
+}
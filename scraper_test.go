@@ -1,12 +1,18 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strings"
+	"sync"
 	"testing"
 	"time"
+
+	"github.com/PuerkitoBio/goquery"
 )
 
 func TestNewScraper(t *testing.T) {
@@ -20,8 +26,9 @@ func TestNewScraper(t *testing.T) {
 		t.Error("client is nil")
 	}
 	
-	if scraper.client.Timeout != 15*time.Second {
-		t.Errorf("expected timeout 15s, got %v", scraper.client.Timeout)
+	// 時間の上限はリクエスト1回ごとに TimeoutTransport で設ける
+	if scraper.client.Timeout != 0 {
+		t.Errorf("expected no client-wide timeout, got %v", scraper.client.Timeout)
 	}
 }
 
@@ -55,18 +62,47 @@ func TestScraper_ScrapeKaikatsuClub_Success(t *testing.T) {
 	}))
 	defer server.Close()
 	
-	_ = NewScraper()
-	// サーバーURLを使用するために関数を修正する必要があるため、
-	// このテストは実際のWebサイトに対するテストとして扱う
-	// 実際の環境では、関数にURLを渡せるようにリファクタリングすることを推奨
+	scraper := NewScraper(WithBaseURL("kaikatsu", server.URL))
+	cafes, err := builtinSource(t, "kaikatsu").Scrape(context.Background(), scraper)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	
+	expected := []NetCafe{
+		{
+			Name:     "新宿西口店",
+			Chain:    Chain{ID: "kaikatsu", Name: "快活CLUB"},
+			Location: "東京都新宿区西新宿1-12-9",
+			Hours:    "24時間営業",
+			Phone:    "03-5321-6166",
+			URL:      server.URL + "/shop/shinjuku-west",
+		},
+		{
+			Name:     "渋谷店",
+			Chain:    Chain{ID: "kaikatsu", Name: "快活CLUB"},
+			Location: "東京都渋谷区渋谷1-1-1",
+			Hours:    "24時間営業",
+			Phone:    "03-1234-5678",
+			URL:      server.URL + "/shop/shibuya",
+		},
+	}
+	if !reflect.DeepEqual(cafes, expected) {
+		t.Errorf("unexpected stores:\ngot: %+v\nexpected: %+v", cafes, expected)
+	}
 }
 
 func TestScraper_ScrapeKaikatsuClub_Error(t *testing.T) {
-	scraper := NewScraper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+	}))
+	defer server.Close()
+	
+	scraper := NewScraper(WithBaseURL("kaikatsu", server.URL))
 	scraper.client.Timeout = 1 * time.Millisecond // タイムアウトを非常に短く設定
 	
-	// 実際のWebサイトへのアクセスは避け、タイムアウトをテスト
-	// 本来はScrapeKaikatsuClub関数をURLを引数に取るように修正すべき
+	if _, err := builtinSource(t, "kaikatsu").Scrape(context.Background(), scraper); err == nil {
+		t.Error("expected timeout error, got nil")
+	}
 }
 
 func TestScraper_ScrapeJiqoo_MockServer(t *testing.T) {
@@ -89,11 +125,44 @@ func TestScraper_ScrapeJiqoo_MockServer(t *testing.T) {
 	</html>
 	`
 	
+	var requestURI string
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestURI = r.URL.RequestURI()
 		w.Header().Set("Content-Type", "text/html")
 		w.Write([]byte(htmlContent))
 	}))
 	defer server.Close()
+	
+	scraper := NewScraper(WithBaseURL("jiqoo", server.URL+"/"))
+	cafes, err := builtinSource(t, "jiqoo").Scrape(context.Background(), scraper)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	
+	if requestURI != "/shop/?pref=13" {
+		t.Errorf("unexpected request URI: %s", requestURI)
+	}
+	
+	expected := []NetCafe{
+		{
+			Name:     "池袋西口ROSA店",
+			Chain:    Chain{ID: "jiqoo", Name: "自遊空間"},
+			Location: "東京都豊島区西池袋1-37-12",
+			Hours:    "24時間営業",
+			Phone:    "03-5391-7778",
+			URL:      server.URL + "/shop/ikebukuro",
+		},
+		{
+			Name:     "新宿東口店",
+			Chain:    Chain{ID: "jiqoo", Name: "自遊空間"},
+			Location: "東京都新宿区新宿3-1-1",
+			Hours:    "24時間営業",
+			Phone:    "03-9876-5432",
+		},
+	}
+	if !reflect.DeepEqual(cafes, expected) {
+		t.Errorf("unexpected stores:\ngot: %+v\nexpected: %+v", cafes, expected)
+	}
 }
 
 func TestScraper_ScrapeManboo_MockServer(t *testing.T) {
@@ -121,6 +190,309 @@ func TestScraper_ScrapeManboo_MockServer(t *testing.T) {
 		w.Write([]byte(htmlContent))
 	}))
 	defer server.Close()
+	
+	scraper := NewScraper(WithBaseURL("manboo", server.URL))
+	cafes, err := builtinSource(t, "manboo").Scrape(context.Background(), scraper)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	
+	expected := []NetCafe{
+		{
+			Name:     "渋谷宮益坂店",
+			Chain:    Chain{ID: "manboo", Name: "マンボー"},
+			Location: "東京都渋谷区渋谷1-12-1",
+			Hours:    "24時間営業",
+			Phone:    "03-5766-6010",
+			URL:      server.URL + "/",
+		},
+		{
+			Name:     "東京都新宿区歌舞伎町店",
+			Chain:    Chain{ID: "manboo", Name: "マンボー"},
+			Hours:    "24時間営業",
+			URL:      server.URL + "/",
+		},
+	}
+	if !reflect.DeepEqual(cafes, expected) {
+		t.Errorf("unexpected stores:\ngot: %+v\nexpected: %+v", cafes, expected)
+	}
+}
+
+func TestScraper_ScrapeDiCE_MockServer(t *testing.T) {
+	htmlContent := `
+	<!DOCTYPE html>
+	<html>
+	<body>
+		<div class="shop-box">
+			<h3 class="shop-name"><a href="/shop/akihabara/">秋葉原店</a></h3>
+			<dl>
+				<dt>住所</dt><dd>〒101-0021 東京都千代田区外神田1-11-5</dd>
+				<dt>営業時間</dt><dd>24時間営業</dd>
+				<dt>TEL</dt><dd>03-5298-1281</dd>
+			</dl>
+		</div>
+		<div class="shop-box">
+			<h3 class="shop-name"><a href="/shop/umeda/">梅田店</a></h3>
+			<dl>
+				<dt>住所</dt><dd>〒530-0012 大阪府大阪市北区芝田1-1-1</dd>
+				<dt>TEL</dt><dd>06-0000-1111</dd>
+			</dl>
+		</div>
+		<div class="shop-box">
+			<h3 class="shop-name"><a href="https://www.diskcity.co.jp/shop/ikebukuro/">DiCE 池袋店</a></h3>
+			<dl>
+				<dt>住所</dt><dd>東京都豊島区東池袋1-1-1</dd>
+				<dt>営業時間</dt><dd>10:00〜翌5:00</dd>
+				<dt>電話番号</dt><dd>03-0000-2222</dd>
+			</dl>
+		</div>
+	</body>
+	</html>
+	`
+	
+	var requestURI string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestURI = r.URL.RequestURI()
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(htmlContent))
+	}))
+	defer server.Close()
+	
+	scraper := NewScraper(WithBaseURL("dice", server.URL))
+	cafes, err := builtinSource(t, "dice").Scrape(context.Background(), scraper)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	
+	if requestURI != "/shop/" {
+		t.Errorf("unexpected request URI: %s", requestURI)
+	}
+	
+	// 全国の一覧から東京都の店舗だけを拾う
+	expected := []NetCafe{
+		{
+			Name:     "秋葉原店",
+			Chain:    Chain{ID: "dice", Name: "DiCE"},
+			Location: "東京都千代田区外神田1-11-5",
+			Hours:    "24時間営業",
+			Phone:    "03-5298-1281",
+			URL:      server.URL + "/shop/akihabara/",
+		},
+		{
+			Name:     "池袋店",
+			Chain:    Chain{ID: "dice", Name: "DiCE"},
+			Location: "東京都豊島区東池袋1-1-1",
+			Hours:    "10:00〜翌5:00",
+			Phone:    "03-0000-2222",
+			URL:      "https://www.diskcity.co.jp/shop/ikebukuro/",
+		},
+	}
+	if !reflect.DeepEqual(cafes, expected) {
+		t.Errorf("unexpected stores:\ngot: %+v\nexpected: %+v", cafes, expected)
+	}
+}
+
+func TestScraper_ScrapeAprecio_MockServer(t *testing.T) {
+	pages := map[string]string{
+		"13": `
+		<ul class="shop-list">
+			<li class="shop">
+				<a class="shop__link" href="/shop/shinjuku-kabukicho/">
+					<p class="shop__name">新宿歌舞伎町店</p>
+					<p class="shop__address">東京都新宿区歌舞伎町1-20-1</p>
+					<p class="shop__hours">24時間営業</p>
+				</a>
+				<p class="shop__tel"><a href="tel:03-5155-4486">電話する</a></p>
+			</li>
+		</ul>`,
+		// 旧レイアウトの表形式
+		"27": `
+		<table class="shop-table">
+			<tr><th>店舗名</th><th>住所</th><th>電話番号</th></tr>
+			<tr>
+				<td class="name"><a href="/shop/namba/">なんば店</a></td>
+				<td class="address">大阪府大阪市中央区難波1-1-1</td>
+				<td class="tel">06-0000-3333</td>
+			</tr>
+		</table>`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/shop/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(pages[r.URL.Query().Get("pref")]))
+	}))
+	defer server.Close()
+	
+	prefs, err := parsePrefectures("東京,大阪")
+	if err != nil {
+		t.Fatal(err)
+	}
+	scraper := NewScraper(WithBaseURL("aprecio", server.URL), WithPrefectures(prefs...))
+	cafes, err := builtinSource(t, "aprecio").Scrape(context.Background(), scraper)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	
+	expected := []NetCafe{
+		{
+			Name:     "新宿歌舞伎町店",
+			Chain:    Chain{ID: "aprecio", Name: "アプレシオ"},
+			Location: "東京都新宿区歌舞伎町1-20-1",
+			Hours:    "24時間営業",
+			Phone:    "03-5155-4486",
+			URL:      server.URL + "/shop/shinjuku-kabukicho/",
+		},
+		{
+			Name:     "なんば店",
+			Chain:    Chain{ID: "aprecio", Name: "アプレシオ"},
+			Location: "大阪府大阪市中央区難波1-1-1",
+			Hours:    "24時間営業",
+			Phone:    "06-0000-3333",
+			URL:      server.URL + "/shop/namba/",
+		},
+	}
+	if !reflect.DeepEqual(cafes, expected) {
+		t.Errorf("unexpected stores:\ngot: %+v\nexpected: %+v", cafes, expected)
+	}
+}
+
+func TestScraper_ScrapeChains_MockServer(t *testing.T) {
+	tests := []struct {
+		chainID    string
+		html       string
+		requestURI string
+		expected   func(serverURL string) []NetCafe
+	}{
+		{
+			chainID: "geragera",
+			html: `
+			<ul class="shoplist">
+				<li>
+					<p class="name"><a href="/shop/tokyo/shinjuku/">新宿西口店</a></p>
+					<p class="add">〒160-0023 東京都新宿区西新宿7-1-1</p>
+					<p class="time">営業時間：24時間営業</p>
+					<p class="tel">TEL：03-0000-4444</p>
+				</li>
+				<li>
+					<p class="name">池袋東口店</p>
+					<p class="add">東京都豊島区南池袋1-1-1</p>
+					<p class="time">10:00〜翌5:00</p>
+				</li>
+			</ul>`,
+			requestURI: "/shop/tokyo/",
+			expected: func(serverURL string) []NetCafe {
+				return []NetCafe{
+					{Name: "新宿西口店", Chain: Chain{ID: "geragera", Name: "ゲラゲラ"}, Location: "東京都新宿区西新宿7-1-1", Hours: "24時間営業", Phone: "03-0000-4444", URL: serverURL + "/shop/tokyo/shinjuku/"},
+					{Name: "池袋東口店", Chain: Chain{ID: "geragera", Name: "ゲラゲラ"}, Location: "東京都豊島区南池袋1-1-1", Hours: "10:00〜翌5:00"},
+				}
+			},
+		},
+		{
+			chainID: "popeye",
+			html: `
+			<table class="shop">
+				<tr><th>店舗名</th><th>住所</th><th>営業時間</th><th>電話番号</th></tr>
+				<tr><td><a href="/shop/kamata/">蒲田店</a></td><td>東京都大田区蒲田5-1-1</td><td>24時間営業</td><td>03-0000-5555</td></tr>
+				<tr><td><a href="/shop/kawasaki/">川崎店</a></td><td>神奈川県川崎市川崎区駅前本町1-1</td><td>24時間営業</td><td>044-000-6666</td></tr>
+			</table>`,
+			requestURI: "/shop/",
+			expected: func(serverURL string) []NetCafe {
+				return []NetCafe{
+					{Name: "蒲田店", Chain: Chain{ID: "popeye", Name: "ポパイ"}, Location: "東京都大田区蒲田5-1-1", Hours: "24時間営業", Phone: "03-0000-5555", URL: serverURL + "/shop/kamata/"},
+				}
+			},
+		},
+		{
+			chainID: "customa",
+			html: `
+			<div class="shop-card">
+				<a class="shop-card__link" href="/shop/ochanomizu/"><h3 class="shop-card__title">御茶ノ水店</h3></a>
+				<p class="shop-card__address">東京都千代田区神田駿河台1-1-1</p>
+				<p class="shop-card__hours">10:00〜23:00</p>
+				<a href="tel:03-0000-7777">電話をかける</a>
+			</div>`,
+			requestURI: "/shop/?area=%E6%9D%B1%E4%BA%AC%E9%83%BD",
+			expected: func(serverURL string) []NetCafe {
+				return []NetCafe{
+					{Name: "御茶ノ水店", Chain: Chain{ID: "customa", Name: "カスタマカフェ"}, Location: "東京都千代田区神田駿河台1-1-1", Hours: "10:00〜23:00", Phone: "03-0000-7777", URL: serverURL + "/shop/ochanomizu/"},
+				}
+			},
+		},
+		{
+			chainID: "cybac",
+			html: `
+			<div class="shop_data">
+				<h2><a href="/shop/tokyo/akiba.html">秋葉原店</a></h2>
+				<table>
+					<tr><th>住所</th><td>東京都千代田区外神田3-1-1</td></tr>
+					<tr><th>営業時間</th><td>24時間営業</td></tr>
+					<tr><th>電話番号</th><td>03-0000-8888</td></tr>
+				</table>
+			</div>`,
+			requestURI: "/shop/tokyo.html",
+			expected: func(serverURL string) []NetCafe {
+				return []NetCafe{
+					{Name: "秋葉原店", Chain: Chain{ID: "cybac", Name: "サイバック"}, Location: "東京都千代田区外神田3-1-1", Hours: "24時間営業", Phone: "03-0000-8888", URL: serverURL + "/shop/tokyo/akiba.html"},
+				}
+			},
+		},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.chainID, func(t *testing.T) {
+			var requestURI string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requestURI = r.URL.RequestURI()
+				w.Header().Set("Content-Type", "text/html")
+				w.Write([]byte(tt.html))
+			}))
+			defer server.Close()
+			
+			scraper := NewScraper(WithBaseURL(tt.chainID, server.URL), WithTransport(http.DefaultTransport))
+			cafes, err := builtinSource(t, tt.chainID).Scrape(context.Background(), scraper)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if requestURI != tt.requestURI {
+				t.Errorf("unexpected request URI: %s", requestURI)
+			}
+			if expected := tt.expected(server.URL); !reflect.DeepEqual(cafes, expected) {
+				t.Errorf("unexpected stores:\ngot: %+v\nexpected: %+v", cafes, expected)
+			}
+		})
+	}
+}
+
+func TestScraper_ScrapeCybac_JSONLD(t *testing.T) {
+	htmlContent := `
+	<html><head>
+	<script type="application/ld+json">
+	[{"@context": "https://schema.org", "@type": "InternetCafe", "name": "新宿店",
+	  "address": "東京都新宿区新宿3-1-1", "telephone": "03-0000-9999", "openingHours": "Mo-Su 00:00-24:00",
+	  "url": "/shop/tokyo/shinjuku.html"}]
+	</script>
+	</head><body>
+		<div class="shop_data"><h2>セレクターの店</h2></div>
+	</body></html>`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(htmlContent))
+	}))
+	defer server.Close()
+	
+	scraper := NewScraper(WithBaseURL("cybac", server.URL), WithTransport(http.DefaultTransport))
+	cafes, err := builtinSource(t, "cybac").Scrape(context.Background(), scraper)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []NetCafe{
+		{Name: "新宿店", Chain: Chain{ID: "cybac", Name: "サイバック"}, Location: "東京都新宿区新宿3-1-1", Hours: "24時間営業", Phone: "03-0000-9999", URL: server.URL + "/shop/tokyo/shinjuku.html"},
+	}
+	if !reflect.DeepEqual(cafes, expected) {
+		t.Errorf("unexpected stores:\ngot: %+v\nexpected: %+v", cafes, expected)
+	}
 }
 
 func TestScraper_ScrapeAll(t *testing.T) {
@@ -131,17 +503,23 @@ func TestScraper_ScrapeAll(t *testing.T) {
 	scraper := NewScraper()
 	scraper.client.Timeout = 1 * time.Millisecond // タイムアウトを短く設定
 	
-	cafes, err := scraper.ScrapeAll()
+	result, err := scraper.ScrapeAll(context.Background())
 	
-	// タイムアウトが発生してもnilエラーを返すことを確認
-	if err != nil {
-		t.Errorf("ScrapeAll should not return error, got %v", err)
+	// すべての取得元がタイムアウトした場合はPartialScrapeErrorで全失敗を報告する
+	var partial *PartialScrapeError
+	if !errors.As(err, &partial) {
+		t.Fatalf("expected *PartialScrapeError, got %v", err)
+	}
+	if !partial.AllFailed() {
+		t.Errorf("expected all sources to fail, got %d of %d", len(partial.Failed), partial.Total)
 	}
 	
-	// 空の配列でもエラーにならないことを確認
-	if cafes == nil {
-		// タイムアウトで取得失敗してもcafesは空の配列になるはず
-		cafes = []NetCafe{}
+	// 取得元ごとの結果は失敗時も返る
+	if len(result.Sources) != len(scraper.sources) {
+		t.Errorf("expected %d source results, got %d", len(scraper.sources), len(result.Sources))
+	}
+	if len(result.Stores) != 0 {
+		t.Errorf("expected no stores, got %d", len(result.Stores))
 	}
 }
 
@@ -170,9 +548,56 @@ func TestScraper_scrapeKaikatsuAlternative(t *testing.T) {
 	</html>
 	`
 	
-	// goquery.Documentを作成するためのテスト
-	reader := strings.NewReader(htmlContent)
-	_ = reader // リーダーを使用してドキュメントを作成
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
+	if err != nil {
+		t.Fatalf("failed to parse HTML: %v", err)
+	}
+	
+	// 一覧の構造が変わったときの予備の解析（組み込み定義の2番目の lists）
+	kaikatsu := builtinSource(t, "kaikatsu")
+	cafes := kaikatsu.parseList(doc, kaikatsu.spec.Lists[1], "http://example.test", nil)
+	if len(cafes) != 2 {
+		t.Fatalf("expected 2 stores, got %d: %+v", len(cafes), cafes)
+	}
+	
+	if cafes[0].Name != "新宿南口店" || cafes[0].Location != "東京都新宿区新宿4-1-1" || cafes[0].Phone != "03-1234-5678" {
+		t.Errorf("unexpected first store: %+v", cafes[0])
+	}
+	if cafes[1].Name != "池袋東口店" || cafes[1].URL != "http://example.test/" {
+		t.Errorf("unexpected second store: %+v", cafes[1])
+	}
+}
+
+func TestScraper_scrapeKaikatsuAlternative_WardInName(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`
+		<ul>
+			<li>
+				快活CLUB 江戸川区役所前店
+				東京都江戸川区中央1-4-1
+				03-1234-5678
+			</li>
+			<li>
+				葛西駅前店
+				江戸川区中葛西3-1-1
+			</li>
+		</ul>
+	`))
+	if err != nil {
+		t.Fatalf("failed to parse HTML: %v", err)
+	}
+
+	// 店名の「区」を住所と取り違えない
+	kaikatsu := builtinSource(t, "kaikatsu")
+	cafes := kaikatsu.parseList(doc, kaikatsu.spec.Lists[1], "http://example.test", nil)
+	if len(cafes) != 2 {
+		t.Fatalf("expected 2 stores, got %d: %+v", len(cafes), cafes)
+	}
+	if cafes[0].Name != "江戸川区役所前店" || cafes[0].Location != "東京都江戸川区中央1-4-1" {
+		t.Errorf("unexpected first store: %+v", cafes[0])
+	}
+	if cafes[1].Name != "葛西駅前店" || cafes[1].Location != "江戸川区中葛西3-1-1" {
+		t.Errorf("unexpected second store: %+v", cafes[1])
+	}
 }
 
 func TestScraper_HTTPStatusError(t *testing.T) {
@@ -182,8 +607,17 @@ func TestScraper_HTTPStatusError(t *testing.T) {
 	}))
 	defer server.Close()
 	
-	// この部分も関数のリファクタリングが必要
-	// URLを引数として受け取るように変更することを推奨
+	scraper := NewScraper(
+		WithBaseURL("kaikatsu", server.URL),
+		WithBaseURL("jiqoo", server.URL),
+		WithBaseURL("manboo", server.URL),
+	)
+	for _, src := range []Source{builtinSource(t, "kaikatsu"), builtinSource(t, "jiqoo"), builtinSource(t, "manboo")} {
+		_, err := src.Scrape(context.Background(), scraper)
+		if err == nil || !strings.Contains(err.Error(), "404") {
+			t.Errorf("%s: expected 404 status error, got %v", src.ChainID(), err)
+		}
+	}
 }
 
 func TestScraper_InvalidHTML(t *testing.T) {
@@ -193,6 +627,15 @@ func TestScraper_InvalidHTML(t *testing.T) {
 		w.Write([]byte("This is not valid HTML"))
 	}))
 	defer server.Close()
+	
+	scraper := NewScraper(WithBaseURL("jiqoo", server.URL))
+	cafes, err := builtinSource(t, "jiqoo").Scrape(context.Background(), scraper)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cafes) != 0 {
+		t.Errorf("expected no stores, got %d", len(cafes))
+	}
 }
 
 func TestScraper_EmptyResponse(t *testing.T) {
@@ -202,6 +645,209 @@ func TestScraper_EmptyResponse(t *testing.T) {
 		w.Write([]byte("<html><body></body></html>"))
 	}))
 	defer server.Close()
+	
+	scraper := NewScraper(WithBaseURL("kaikatsu", server.URL))
+	cafes, err := builtinSource(t, "kaikatsu").Scrape(context.Background(), scraper)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cafes) != 0 {
+		t.Errorf("expected no stores, got %d", len(cafes))
+	}
+}
+
+type fakeSource struct {
+	name    string
+	chainID string
+	cafes   []NetCafe
+	err     error
+	scrape  func(ctx context.Context) ([]NetCafe, error)
+}
+
+func (f fakeSource) Name() string    { return f.name }
+func (f fakeSource) ChainID() string { return f.chainID }
+func (f fakeSource) BaseURL() string { return "http://example.test" }
+
+func (f fakeSource) Scrape(ctx context.Context, s *Scraper) ([]NetCafe, error) {
+	if f.scrape != nil {
+		return f.scrape(ctx)
+	}
+	return f.cafes, f.err
+}
+
+func TestRegisteredSources(t *testing.T) {
+	sources := RegisteredSources()
+
+	expected := []string{"kaikatsu", "jiqoo", "manboo", "dice", "aprecio", "geragera", "popeye", "customa", "cybac"}
+	if len(sources) < len(expected) {
+		t.Fatalf("expected at least %d sources, got %d", len(expected), len(sources))
+	}
+	for i, id := range expected {
+		if sources[i].ChainID() != id {
+			t.Errorf("source %d: expected %s, got %s", i, id, sources[i].ChainID())
+		}
+	}
+}
+
+func TestScraper_baseURL(t *testing.T) {
+	scraper := NewScraper(WithBaseURL("jiqoo", "http://localhost:8080/"))
+
+	if got := scraper.baseURL(builtinSource(t, "jiqoo")); got != "http://localhost:8080" {
+		t.Errorf("expected overridden base URL, got %s", got)
+	}
+	if got := scraper.baseURL(builtinSource(t, "kaikatsu")); got != "https://www.kaikatsu.jp" {
+		t.Errorf("expected default base URL, got %s", got)
+	}
+}
+
+func TestRegisterSource_Duplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for duplicate chain ID")
+		}
+	}()
+	RegisterSource(fakeSource{name: "快活CLUB", chainID: "kaikatsu"})
+}
+
+func TestScraper_ScrapeAll_Sources(t *testing.T) {
+	scraper := NewScraper()
+	scraper.sources = []Source{
+		fakeSource{name: "テストA", chainID: "a", cafes: []NetCafe{{Name: "A店"}}},
+		fakeSource{name: "テストB", chainID: "b", err: fmt.Errorf("boom")},
+		fakeSource{name: "テストC", chainID: "c", cafes: []NetCafe{{Name: "C1店"}, {Name: "C2店"}}},
+	}
+
+	result, err := scraper.ScrapeAll(context.Background())
+
+	var partial *PartialScrapeError
+	if !errors.As(err, &partial) {
+		t.Fatalf("expected *PartialScrapeError, got %v", err)
+	}
+	if partial.AllFailed() || len(partial.Failed) != 1 || partial.Failed[0].ChainID != "b" {
+		t.Errorf("unexpected failed sources: %+v", partial.Failed)
+	}
+	if !strings.Contains(err.Error(), "1 of 3 sources failed: テストB: boom") {
+		t.Errorf("unexpected error message: %v", err)
+	}
+
+	var names []string
+	for _, cafe := range result.Stores {
+		names = append(names, cafe.Name)
+	}
+	if strings.Join(names, ",") != "A店,C1店,C2店" {
+		t.Errorf("unexpected stores: %v", names)
+	}
+
+	counts := []int{1, 0, 2}
+	for i, r := range result.Sources {
+		if r.Count != counts[i] {
+			t.Errorf("source %s: expected count %d, got %d", r.ChainID, counts[i], r.Count)
+		}
+	}
+	if result.Sources[1].OK() {
+		t.Error("expected source b to report failure")
+	}
+}
+
+func TestScraper_ScrapeAll_Order(t *testing.T) {
+	scraper := NewScraper()
+	scraper.sources = []Source{
+		fakeSource{name: "テストA", chainID: "a", scrape: func(ctx context.Context) ([]NetCafe, error) {
+			time.Sleep(20 * time.Millisecond)
+			return []NetCafe{{Name: "A2店"}, {Name: "A1店"}}, nil
+		}},
+		fakeSource{name: "テストB", chainID: "b", cafes: []NetCafe{{Name: "B2店"}, {Name: "B1店"}}},
+	}
+
+	result, err := scraper.ScrapeAll(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var names []string
+	for _, cafe := range result.Stores {
+		names = append(names, cafe.Name)
+	}
+	if strings.Join(names, ",") != "A1店,A2店,B1店,B2店" {
+		t.Errorf("unexpected order: %v", names)
+	}
+	if d := result.Sources[0].Duration; d < 20*time.Millisecond {
+		t.Errorf("expected duration of at least 20ms, got %v", d)
+	}
+}
+
+func TestScraper_ScrapeAll_Normalize(t *testing.T) {
+	scraper := NewScraper()
+	scraper.sources = []Source{
+		fakeSource{name: "テストA", chainID: "a", cafes: []NetCafe{{Name: "A店", Location: "東京都豊島区西池袋1-37-12"}}},
+	}
+
+	result, err := scraper.ScrapeAll(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if addr := result.Stores[0].Address; addr == nil || addr.City != "豊島区" {
+		t.Errorf("expected parsed address, got %+v", addr)
+	}
+}
+
+func TestScraper_ScrapeAll_Concurrency(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	scrape := func(ctx context.Context) ([]NetCafe, error) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		return nil, nil
+	}
+
+	scraper := NewScraper(WithConcurrency(2))
+	scraper.sources = nil
+	for i := 0; i < 6; i++ {
+		scraper.sources = append(scraper.sources, fakeSource{chainID: fmt.Sprint(i), scrape: scrape})
+	}
+
+	if _, err := scraper.ScrapeAll(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if maxInFlight != 2 {
+		t.Errorf("expected at most 2 concurrent scrapes, got %d", maxInFlight)
+	}
+}
+
+func TestScraper_ScrapeAll_Canceled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	scraper := NewScraper(WithBaseURL("kaikatsu", server.URL))
+	scraper.sources = []Source{builtinSource(t, "kaikatsu")}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := scraper.ScrapeAll(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+	var partial *PartialScrapeError
+	if !errors.As(err, &partial) || !partial.AllFailed() {
+		t.Errorf("expected all sources to fail, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("ScrapeAll did not honor context deadline, took %v", elapsed)
+	}
 }
 
 // ベンチマークテスト
@@ -214,12 +860,13 @@ func BenchmarkScraper_ParseHTML(b *testing.B) {
 	}))
 	defer server.Close()
 	
-	scraper := NewScraper()
+	scraper := NewScraper(WithBaseURL("kaikatsu", server.URL))
 	
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		// パフォーマンステストの実行
-		_ = scraper
+		if _, err := builtinSource(b, "kaikatsu").Scrape(context.Background(), scraper); err != nil {
+			b.Fatal(err)
+		}
 	}
 }
 
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Server は NetCafeService の店舗情報を REST API として公開する。
+type Server struct {
+	service *NetCafeService
+	mux     *http.ServeMux
+	now     func() time.Time
+	// refresher が設定されていれば GET /status に再取得の状況を含める。
+	refresher *Refresher
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type storesResponse struct {
+	Count  int       `json:"count"`
+	Stores []NetCafe `json:"stores"`
+}
+
+type statusResponse struct {
+	Stores  int            `json:"stores"`
+	Refresh *RefreshStatus `json:"refresh,omitempty"`
+}
+
+func NewServer(service *NetCafeService) *Server {
+	s := &Server{
+		service: service,
+		mux:     http.NewServeMux(),
+		now:     time.Now,
+	}
+	s.mux.HandleFunc("/stores", s.handleStores)
+	s.mux.HandleFunc("/stores/{id}", s.handleStore)
+	s.mux.HandleFunc("/search", s.handleSearch)
+	s.mux.HandleFunc("/status", s.handleStatus)
+	s.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "not found: "+r.URL.Path)
+	})
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	defer func() {
+		if v := recover(); v != nil {
+			log.Printf("panic serving %s %s: %v", r.Method, r.URL.Path, v)
+			writeError(w, http.StatusInternalServerError, "internal server error")
+		}
+	}()
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed: "+r.Method)
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// handleStores は GET /stores。/search と同じ絞り込みパラメータ（ward, chain, open_now）を受け付ける。
+func (s *Server) handleStores(w http.ResponseWriter, r *http.Request) {
+	cafes, err := s.filter(s.service.GetAll(), r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, storesResponse{Count: len(cafes), Stores: cafes})
+}
+
+// handleStore は GET /stores/{id}。id は店舗の ID（NetCafe.ID）。
+func (s *Server) handleStore(w http.ResponseWriter, r *http.Request) {
+	cafe, ok := s.service.GetByID(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("store %s not found", r.PathValue("id")))
+		return
+	}
+	writeJSON(w, http.StatusOK, cafe)
+}
+
+// handleSearch は GET /search?q=...。q は店舗名・住所・電話番号に対する検索語。
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query().Get("q")
+	if q == "" {
+		writeError(w, http.StatusBadRequest, "missing required parameter: q")
+		return
+	}
+
+	cafes, err := s.filter(s.service.SearchByName(q), r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, storesResponse{Count: len(cafes), Stores: cafes})
+}
+
+// handleStatus は GET /status。店舗数と、定期再取得をしていればその状況を返す。
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	resp := statusResponse{Stores: len(s.service.GetAll())}
+	if s.refresher != nil {
+		status := s.refresher.Status()
+		resp.Refresh = &status
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) filter(cafes []NetCafe, r *http.Request) ([]NetCafe, error) {
+	query := r.URL.Query()
+	if ward := query.Get("ward"); ward != "" {
+		cafes = filterByWard(cafes, ward)
+	}
+	if chain := query.Get("chain"); chain != "" {
+		ids, err := parseChains(chain, s.chains())
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) > 0 {
+			cafes = filterByChain(cafes, ids)
+		}
+	}
+	if v := query.Get("open_now"); v != "" {
+		openNow, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid open_now: %s", v)
+		}
+		if openNow {
+			cafes = filterOpenAt(cafes, s.now())
+		}
+	}
+	if cafes == nil {
+		cafes = []NetCafe{}
+	}
+	return cafes, nil
+}
+
+// chains は chain パラメーターで指定できるチェーン。登録済みの取得元と、配信中の店舗のチェーン。
+func (s *Server) chains() []Chain {
+	var chains []Chain
+	seen := make(map[string]bool)
+	add := func(c Chain) {
+		if c.ID != "" && !seen[c.ID] {
+			seen[c.ID] = true
+			chains = append(chains, c)
+		}
+	}
+	for _, src := range RegisteredSources() {
+		add(sourceChain(src))
+	}
+	for _, cafe := range s.service.GetAll() {
+		add(cafe.Chain)
+	}
+	return chains
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("failed to encode response: %v", err)
+		status = http.StatusInternalServerError
+		body, _ = json.Marshal(errorResponse{Error: "failed to encode response"})
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(body)
+	w.Write([]byte("\n"))
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, errorResponse{Error: message})
+}
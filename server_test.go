@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestServer() *Server {
+	s := NewServer(NewNetCafeService())
+	s.now = func() time.Time { return jstTime(2026, 10, 20, 3, 0) }
+	return s
+}
+
+func doRequest(t *testing.T, s *Server, method, target string, v any) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json; charset=utf-8" {
+		t.Errorf("%s %s: unexpected content type %q", method, target, ct)
+	}
+	if v != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatalf("%s %s: invalid JSON body: %v\n%s", method, target, err, rec.Body.String())
+		}
+	}
+	return rec
+}
+
+func TestServer_Stores(t *testing.T) {
+	var body storesResponse
+	rec := doRequest(t, newTestServer(), http.MethodGet, "/stores", &body)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if body.Count != 5 || len(body.Stores) != 5 {
+		t.Errorf("expected 5 stores, got %d/%d", body.Count, len(body.Stores))
+	}
+}
+
+func TestServer_StoresFilter(t *testing.T) {
+	var body storesResponse
+	doRequest(t, newTestServer(), http.MethodGet, "/stores?ward=新宿区&open_now=true", &body)
+
+	if body.Count != 2 {
+		t.Errorf("expected 2 stores, got %d", body.Count)
+	}
+}
+
+func TestServer_StoresChainFilter(t *testing.T) {
+	var body storesResponse
+	doRequest(t, newTestServer(), http.MethodGet, "/stores?chain=kaikatsu,DiCE", &body)
+
+	if body.Count != 2 || body.Stores[0].Chain.ID != "kaikatsu" || body.Stores[1].Chain.ID != "dice" {
+		t.Errorf("unexpected stores: %+v", body)
+	}
+
+	var errBody errorResponse
+	rec := doRequest(t, newTestServer(), http.MethodGet, "/stores?chain=unknown", &errBody)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(errBody.Error, `unknown chain "unknown"`) {
+		t.Errorf("expected 400 for an unknown chain, got %d %+v", rec.Code, errBody)
+	}
+}
+
+func TestServer_Store(t *testing.T) {
+	s := newTestServer()
+	id := s.service.GetAll()[2].ID
+
+	var cafe NetCafe
+	rec := doRequest(t, s, http.MethodGet, "/stores/"+id, &cafe)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if cafe.Name != "秋葉原店" || cafe.ID != id {
+		t.Errorf("unexpected store: %+v", cafe)
+	}
+}
+
+func TestServer_Search(t *testing.T) {
+	var body storesResponse
+	rec := doRequest(t, newTestServer(), http.MethodGet, "/search?q=渋谷", &body)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if body.Count != 1 || body.Stores[0].Name != "渋谷宮益坂店" {
+		t.Errorf("unexpected search result: %+v", body)
+	}
+
+	doRequest(t, newTestServer(), http.MethodGet, "/search?q=横浜", &body)
+	if body.Count != 0 || body.Stores == nil {
+		t.Errorf("expected empty store list, got %+v", body)
+	}
+}
+
+func TestServer_Errors(t *testing.T) {
+	tests := []struct {
+		method string
+		target string
+		status int
+	}{
+		{http.MethodGet, "/stores/abc", http.StatusNotFound},
+		{http.MethodGet, "/stores/99", http.StatusNotFound},
+		{http.MethodGet, "/stores/dice-unknown", http.StatusNotFound},
+		{http.MethodGet, "/search", http.StatusBadRequest},
+		{http.MethodGet, "/search?q=新宿&open_now=maybe", http.StatusBadRequest},
+		{http.MethodGet, "/stores?chain=kaikatsu,unknown", http.StatusBadRequest},
+		{http.MethodGet, "/search?q=新宿&chain=unknown", http.StatusBadRequest},
+		{http.MethodGet, "/unknown", http.StatusNotFound},
+		{http.MethodPost, "/stores", http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.target, func(t *testing.T) {
+			var body errorResponse
+			rec := doRequest(t, newTestServer(), tt.method, tt.target, &body)
+			if rec.Code != tt.status {
+				t.Errorf("expected %d, got %d", tt.status, rec.Code)
+			}
+			if body.Error == "" {
+				t.Error("expected error message in body")
+			}
+		})
+	}
+}
+
+func TestServer_Panic(t *testing.T) {
+	s := newTestServer()
+	s.mux.HandleFunc("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	var body errorResponse
+	rec := doRequest(t, s, http.MethodGet, "/panic", &body)
+	if rec.Code != http.StatusInternalServerError || body.Error == "" {
+		t.Errorf("expected 500 with error body, got %d %+v", rec.Code, body)
+	}
+}
+
+func TestServer_Status(t *testing.T) {
+	s := newTestServer()
+
+	var body statusResponse
+	doRequest(t, s, http.MethodGet, "/status", &body)
+	if body.Stores != 5 || body.Refresh != nil {
+		t.Errorf("unexpected status without refresher: %+v", body)
+	}
+
+	scraper := NewScraper()
+	scraper.sources = []Source{fakeSource{chainID: "a", cafes: []NetCafe{{Name: "A店"}}}}
+	s.refresher = NewRefresher(s.service, scraper, time.Hour, 0)
+	if err := s.refresher.Refresh(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	body = statusResponse{}
+	doRequest(t, s, http.MethodGet, "/status", &body)
+	if body.Stores != 1 || body.Refresh == nil || body.Refresh.LastSuccess.IsZero() {
+		t.Errorf("unexpected status with refresher: %+v", body)
+	}
+}
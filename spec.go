@@ -0,0 +1,429 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"gopkg.in/yaml.v3"
+)
+
+// builtinSourceSpecs は組み込みの取得元の定義（sources.yaml）。
+//
+//go:embed sources.yaml
+var builtinSourceSpecs []byte
+
+func init() {
+	specs, err := parseSourceSpecs(builtinSourceSpecs, "sources.yaml")
+	if err != nil {
+		panic("netcafe: invalid built-in sources: " + err.Error())
+	}
+	for _, spec := range specs {
+		RegisterSource(specSource{spec: spec})
+	}
+}
+
+// SourceSpec は設定ファイルで定義する取得元。specSource が汎用の解析エンジンとして実行する。
+type SourceSpec struct {
+	ID      string `yaml:"id" json:"id"`
+	Name    string `yaml:"name" json:"name"`
+	BaseURL string `yaml:"base_url" json:"base_url"`
+	// NamePrefix は廃止。店名のチェーン名は Name で扱う。以前の定義ファイルを読み込めるように受け付けるだけで使わない。
+	NamePrefix string `yaml:"name_prefix" json:"name_prefix"`
+	// StartURL は一覧ページのパス。{pref_slug}（tokyo）・{pref_code}（13）・{pref_name}（東京都）を含むと
+	// -pref の都道府県ごとに取得する。
+	StartURL string `yaml:"start_url" json:"start_url"`
+	// FilterByPrefecture は全国の店舗が1つの一覧に載っている場合に、-pref の都道府県にある項目だけを残す。
+	// 住所が取れればその先頭の都道府県で、取れなければ項目のテキストに都道府県名を含むかで判定する。
+	FilterByPrefecture bool `yaml:"filter_by_prefecture" json:"filter_by_prefecture"`
+	// Lists は一覧の解析方法。上から順に試し、最初に店舗が見つかったものを使う。
+	Lists  []ListSpec  `yaml:"lists" json:"lists"`
+	Detail *DetailSpec `yaml:"detail" json:"detail"`
+}
+
+// ListSpec は一覧ページの店舗1件に当たる要素と、その中の各項目の取り出し方。
+type ListSpec struct {
+	Item string `yaml:"item" json:"item"`
+	// Match は項目のテキストが一致しなければ読み飛ばす正規表現。
+	Match  string     `yaml:"match" json:"match"`
+	Fields FieldSpecs `yaml:"fields" json:"fields"`
+
+	match *regexp.Regexp
+}
+
+// FieldSpecs は NetCafe の各フィールドの取り出し方。候補を上から順に試す。
+type FieldSpecs struct {
+	Name    []Extractor `yaml:"name" json:"name"`
+	Address []Extractor `yaml:"address" json:"address"`
+	Hours   []Extractor `yaml:"hours" json:"hours"`
+	Phone   []Extractor `yaml:"phone" json:"phone"`
+	URL     []Extractor `yaml:"url" json:"url"`
+}
+
+// Extractor は項目の要素から値を1つ取り出す。Value があれば固定値、なければ Selector
+// （空なら項目の要素自身）のテキストまたは Attr 属性の値を Pattern で絞り込む。
+// Pattern にサブマッチがあれば最初のサブマッチを、なければ一致した部分全体を使う。
+type Extractor struct {
+	Selector string `yaml:"selector" json:"selector"`
+	Attr     string `yaml:"attr" json:"attr"`
+	Pattern  string `yaml:"pattern" json:"pattern"`
+	Value    string `yaml:"value" json:"value"`
+
+	pattern *regexp.Regexp
+}
+
+// DetailSpec は詳細ページの各項目を探すセレクター。見つからなければ dt/dd の見出しで探す。
+type DetailSpec struct {
+	Location  string `yaml:"location" json:"location"`
+	Hours     string `yaml:"hours" json:"hours"`
+	Phone     string `yaml:"phone" json:"phone"`
+	Amenities string `yaml:"amenities" json:"amenities"`
+	Prices    string `yaml:"prices" json:"prices"`
+}
+
+var chainIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// LoadSourceSpecs は path の設定ファイルから取得元の定義を読み込む。path がディレクトリなら
+// その中の *.yaml・*.yml・*.json をファイル名順にすべて読む。
+func LoadSourceSpecs(path string) ([]SourceSpec, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{path}
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		files = nil
+		for _, e := range entries {
+			switch strings.ToLower(filepath.Ext(e.Name())) {
+			case ".yaml", ".yml", ".json":
+				if !e.IsDir() {
+					files = append(files, filepath.Join(path, e.Name()))
+				}
+			}
+		}
+		sort.Strings(files)
+	}
+
+	var specs []SourceSpec
+	seen := map[string]string{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		loaded, err := parseSourceSpecs(data, file)
+		if err != nil {
+			return nil, err
+		}
+		for _, spec := range loaded {
+			if prev, ok := seen[spec.ID]; ok {
+				return nil, fmt.Errorf("%s: source %q is already defined in %s", file, spec.ID, prev)
+			}
+			seen[spec.ID] = file
+			specs = append(specs, spec)
+		}
+	}
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("%s: no source definitions found", path)
+	}
+	return specs, nil
+}
+
+// parseSourceSpecs は「sources:」の下に取得元を並べた設定ファイルを解析する。
+// name の拡張子が .json なら JSON、それ以外は YAML として読み、知らないキーはエラーにする。
+func parseSourceSpecs(data []byte, name string) ([]SourceSpec, error) {
+	var file struct {
+		Sources []SourceSpec `yaml:"sources" json:"sources"`
+	}
+	var err error
+	if strings.EqualFold(filepath.Ext(name), ".json") {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&file)
+	} else {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err = dec.Decode(&file); errors.Is(err, io.EOF) {
+			err = nil
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	seen := map[string]bool{}
+	for i := range file.Sources {
+		spec := &file.Sources[i]
+		if err := spec.compile(); err != nil {
+			return nil, fmt.Errorf("%s: source %d (%s): %w", name, i+1, spec.ID, err)
+		}
+		if seen[spec.ID] {
+			return nil, fmt.Errorf("%s: source %q is defined twice", name, spec.ID)
+		}
+		seen[spec.ID] = true
+	}
+	return file.Sources, nil
+}
+
+// compile は定義を検証し、正規表現をコンパイルしておく。
+func (spec *SourceSpec) compile() error {
+	if !chainIDPattern.MatchString(spec.ID) {
+		return fmt.Errorf("id must be lowercase letters, digits, '-' or '_', got %q", spec.ID)
+	}
+	if spec.Name == "" {
+		return errors.New("name is required")
+	}
+	u, err := url.Parse(spec.BaseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("base_url must be an absolute http(s) URL, got %q", spec.BaseURL)
+	}
+	spec.BaseURL = strings.TrimSuffix(spec.BaseURL, "/")
+	if spec.StartURL == "" {
+		return errors.New("start_url is required")
+	}
+	if len(spec.Lists) == 0 {
+		return errors.New("at least one entry in lists is required")
+	}
+
+	for i := range spec.Lists {
+		l := &spec.Lists[i]
+		if err := validateSelector(l.Item); err != nil || l.Item == "" {
+			return fmt.Errorf("lists[%d].item: invalid selector %q", i, l.Item)
+		}
+		if l.Match != "" {
+			if l.match, err = regexp.Compile(l.Match); err != nil {
+				return fmt.Errorf("lists[%d].match: %w", i, err)
+			}
+		}
+		if len(l.Fields.Name) == 0 {
+			return fmt.Errorf("lists[%d].fields.name is required", i)
+		}
+		for field, extractors := range map[string][]Extractor{
+			"name": l.Fields.Name, "address": l.Fields.Address, "hours": l.Fields.Hours,
+			"phone": l.Fields.Phone, "url": l.Fields.URL,
+		} {
+			for j := range extractors {
+				if err := extractors[j].compile(); err != nil {
+					return fmt.Errorf("lists[%d].fields.%s[%d]: %w", i, field, j, err)
+				}
+			}
+		}
+	}
+
+	if d := spec.Detail; d != nil {
+		for _, sel := range []string{d.Location, d.Hours, d.Phone, d.Amenities, d.Prices} {
+			if err := validateSelector(sel); err != nil {
+				return fmt.Errorf("detail: invalid selector %q", sel)
+			}
+		}
+	}
+	return nil
+}
+
+func (e *Extractor) compile() error {
+	if e.Value != "" {
+		if e.Selector != "" || e.Attr != "" || e.Pattern != "" {
+			return errors.New("value cannot be combined with selector, attr or pattern")
+		}
+		return nil
+	}
+	if err := validateSelector(e.Selector); err != nil {
+		return fmt.Errorf("invalid selector %q", e.Selector)
+	}
+	if e.Pattern != "" {
+		var err error
+		if e.pattern, err = regexp.Compile(e.Pattern); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateSelector は CSS セレクターとして解析できるかどうかを確かめる。空文字列は省略とみなす。
+func validateSelector(sel string) error {
+	if sel == "" {
+		return nil
+	}
+	_, err := cascadia.ParseGroup(sel)
+	return err
+}
+
+// WithSourceSpecs は定義から作った取得元を加える。同じ id の取得元があれば置き換える。
+func WithSourceSpecs(specs ...SourceSpec) ScraperOption {
+	return func(s *Scraper) {
+		for _, spec := range specs {
+			src := specSource{spec: spec}
+			replaced := false
+			for i, existing := range s.sources {
+				if existing.ChainID() == spec.ID {
+					s.sources[i] = src
+					replaced = true
+				}
+			}
+			if !replaced {
+				s.sources = append(s.sources, src)
+			}
+		}
+	}
+}
+
+// specSource は SourceSpec に従って一覧・詳細ページを解析する Source。
+type specSource struct {
+	spec SourceSpec
+}
+
+func (src specSource) Name() string    { return src.spec.Name }
+func (src specSource) ChainID() string { return src.spec.ID }
+func (src specSource) BaseURL() string { return src.spec.BaseURL }
+
+func (src specSource) Scrape(ctx context.Context, s *Scraper) ([]NetCafe, error) {
+	base := s.baseURL(src)
+	if !strings.Contains(src.spec.StartURL, "{pref_") {
+		var prefs []Prefecture
+		if src.spec.FilterByPrefecture {
+			prefs = s.prefectures
+		}
+		var cafes []NetCafe
+		_, err := s.fetchPages(ctx, absoluteURL(base, src.spec.StartURL), func(doc *goquery.Document) {
+			cafes = append(cafes, src.parsePage(doc, base, prefs)...)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return dedupeCafes(cafes), nil
+	}
+
+	return s.scrapePrefectures(ctx, func(pref Prefecture) ([]NetCafe, error) {
+		var cafes []NetCafe
+		_, err := s.fetchPages(ctx, absoluteURL(base, src.spec.startURL(pref)), func(doc *goquery.Document) {
+			cafes = append(cafes, src.parsePage(doc, base, nil)...)
+		})
+		return cafes, err
+	})
+}
+
+func (spec SourceSpec) startURL(pref Prefecture) string {
+	return strings.NewReplacer(
+		"{pref_slug}", pref.Slug,
+		"{pref_code}", strconv.Itoa(pref.Code),
+		"{pref_name}", url.QueryEscape(pref.Name),
+	).Replace(spec.StartURL)
+}
+
+// parsePage は一覧ページから店舗を取り出す。構造化データがあればそれを使い、なければ Lists を順に試す。
+// prefs を渡すと、その都道府県にある店舗だけを残す。
+func (src specSource) parsePage(doc *goquery.Document, base string, prefs []Prefecture) []NetCafe {
+	if page := structuredStores(doc, src, base); len(page) > 0 {
+		if prefs == nil {
+			return page
+		}
+		var cafes []NetCafe
+		for _, c := range page {
+			if locatedInAny(c.Location, c.Location, prefs) {
+				cafes = append(cafes, c)
+			}
+		}
+		return cafes
+	}
+
+	for _, l := range src.spec.Lists {
+		if page := src.parseList(doc, l, base, prefs); len(page) > 0 {
+			return page
+		}
+	}
+	return nil
+}
+
+func (src specSource) parseList(doc *goquery.Document, l ListSpec, base string, prefs []Prefecture) []NetCafe {
+	var cafes []NetCafe
+	doc.Find(l.Item).Each(func(i int, item *goquery.Selection) {
+		text := item.Text()
+		if l.match != nil && !l.match.MatchString(text) {
+			return
+		}
+
+		name := extract(item, l.Fields.Name)
+		if name == "" {
+			return
+		}
+		location := extract(item, l.Fields.Address)
+		if prefs != nil && !locatedInAny(location, text, prefs) {
+			return
+		}
+		cafes = append(cafes, NetCafe{
+			Name:     trimChainName(name, sourceChain(src)),
+			Chain:    sourceChain(src),
+			Location: location,
+			Hours:    extract(item, l.Fields.Hours),
+			Phone:    extract(item, l.Fields.Phone),
+			URL:      absoluteURL(base, extract(item, l.Fields.URL)),
+		})
+	})
+	return cafes
+}
+
+// extract は候補を上から順に試し、最初に得られた空でない値を返す。
+func extract(item *goquery.Selection, candidates []Extractor) string {
+	for _, e := range candidates {
+		if v := e.extract(item); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func (e Extractor) extract(item *goquery.Selection) string {
+	if e.Value != "" {
+		return e.Value
+	}
+	sel := item
+	if e.Selector != "" {
+		sel = item.Find(e.Selector).First()
+	}
+	if sel.Length() == 0 {
+		return ""
+	}
+
+	var text string
+	if e.Attr != "" {
+		text = sel.AttrOr(e.Attr, "")
+	} else {
+		text = sel.Text()
+	}
+	if e.pattern != nil {
+		m := e.pattern.FindStringSubmatch(text)
+		switch {
+		case m == nil:
+			return ""
+		case len(m) > 1:
+			text = m[1]
+		default:
+			text = m[0]
+		}
+	}
+	return strings.TrimSpace(text)
+}
+
+func (src specSource) ParseDetail(doc *goquery.Document) StoreDetail {
+	if src.spec.Detail == nil {
+		return parseDetail(doc, DetailSpec{})
+	}
+	return parseDetail(doc, *src.spec.Detail)
+}
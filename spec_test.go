@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// builtinSource は組み込みの定義（sources.yaml）から作った取得元を返す。
+func builtinSource(t testing.TB, chainID string) specSource {
+	t.Helper()
+	for _, src := range RegisteredSources() {
+		if s, ok := src.(specSource); ok && s.ChainID() == chainID {
+			return s
+		}
+	}
+	t.Fatalf("built-in source %q not found", chainID)
+	return specSource{}
+}
+
+func writeSpecFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestBuiltinSourceSpecs(t *testing.T) {
+	specs, err := parseSourceSpecs(builtinSourceSpecs, "sources.yaml")
+	if err != nil {
+		t.Fatalf("built-in sources should be valid: %v", err)
+	}
+	var ids []string
+	for _, spec := range specs {
+		ids = append(ids, spec.ID)
+	}
+	if strings.Join(ids, ",") != "kaikatsu,jiqoo,manboo,dice,aprecio,geragera,popeye,customa,cybac" {
+		t.Errorf("unexpected built-in sources: %v", ids)
+	}
+	if _, ok := RegisteredSources()[0].(DetailParser); !ok {
+		t.Error("spec sources should parse detail pages")
+	}
+}
+
+func TestLoadSourceSpecs_NewChain(t *testing.T) {
+	dir := t.TempDir()
+	writeSpecFile(t, dir, "aircafe.yaml", `
+sources:
+  - id: aircafe
+    name: アイカフェ
+    base_url: https://www.aircafe.example/
+    start_url: /stores/{pref_slug}.html
+    lists:
+      - item: table.stores tr
+        match: '\d'
+        fields:
+          name:
+            - selector: td.name a
+          address:
+            - selector: td.addr
+              pattern: '〒\d{3}-\d{4}\s*(.+)'
+            - selector: td.addr
+          hours:
+            - selector: td.hours
+            - value: 24時間営業
+          phone:
+            - selector: td.tel a
+              attr: href
+              pattern: 'tel:(.+)'
+          url:
+            - selector: td.name a
+              attr: href
+`)
+	writeSpecFile(t, dir, "notes.txt", "読み込まれない")
+	writeSpecFile(t, dir, "other.json", `{"sources": [{
+		"id": "other", "name": "ほか", "base_url": "http://other.example", "start_url": "/",
+		"lists": [{"item": "li", "fields": {"name": [{"selector": ""}], "url": [{"value": "/"}]}}]
+	}]}`)
+
+	specs, err := LoadSourceSpecs(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(specs) != 2 || specs[0].ID != "aircafe" || specs[1].ID != "other" {
+		t.Fatalf("unexpected specs: %+v", specs)
+	}
+
+	var requestURI string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestURI = r.URL.RequestURI()
+		w.Write([]byte(`
+		<table class="stores">
+			<tr><th>店舗</th><th>住所</th></tr>
+			<tr>
+				<td class="name"><a href="/stores/akihabara/">秋葉原店</a></td>
+				<td class="addr">〒101-0021 東京都千代田区外神田1-1-1</td>
+				<td class="hours">10:00〜翌5:00</td>
+				<td class="tel"><a href="tel:03-0000-1111">電話する</a></td>
+			</tr>
+			<tr>
+				<td class="name"><a href="https://www.aircafe.example/stores/ueno/">アイカフェ 上野店</a></td>
+				<td class="addr">東京都台東区上野1-1-1</td>
+			</tr>
+		</table>`))
+	}))
+	defer server.Close()
+
+	scraper := NewScraper(WithSourceSpecs(specs...), WithBaseURL("aircafe", server.URL), WithTransport(http.DefaultTransport))
+	var src Source
+	for _, s := range scraper.sources {
+		if s.ChainID() == "aircafe" {
+			src = s
+		}
+	}
+	if src == nil || len(scraper.sources) != len(RegisteredSources())+2 {
+		t.Fatalf("expected the new sources to be added, got %d sources", len(scraper.sources))
+	}
+
+	cafes, err := src.Scrape(context.Background(), scraper)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if requestURI != "/stores/tokyo.html" {
+		t.Errorf("unexpected request URI: %s", requestURI)
+	}
+	expected := []NetCafe{
+		{
+			Name:     "秋葉原店",
+			Chain:    Chain{ID: "aircafe", Name: "アイカフェ"},
+			Location: "東京都千代田区外神田1-1-1",
+			Hours:    "10:00〜翌5:00",
+			Phone:    "03-0000-1111",
+			URL:      server.URL + "/stores/akihabara/",
+		},
+		{
+			Name:     "上野店",
+			Chain:    Chain{ID: "aircafe", Name: "アイカフェ"},
+			Location: "東京都台東区上野1-1-1",
+			Hours:    "24時間営業",
+			URL:      "https://www.aircafe.example/stores/ueno/",
+		},
+	}
+	if !reflect.DeepEqual(cafes, expected) {
+		t.Errorf("unexpected stores:\n got %+v\nwant %+v", cafes, expected)
+	}
+}
+
+func TestWithSourceSpecs_ReplaceBuiltin(t *testing.T) {
+	// サイトのマークアップが変わっても、定義を差し替えれば再ビルドせずに追従できる
+	specs, err := parseSourceSpecs([]byte(`
+sources:
+  - id: kaikatsu
+    name: 快活CLUB
+    base_url: https://www.kaikatsu.jp
+    start_url: /shop/{pref_slug}/
+    lists:
+      - item: article.store
+        fields:
+          name:
+            - selector: .store-title
+          hours:
+            - value: 24時間営業
+`), "kaikatsu.yaml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<article class="store"><h2 class="store-title">新宿西口店</h2></article>`))
+	}))
+	defer server.Close()
+
+	scraper := NewScraper(WithSourceSpecs(specs...), WithBaseURL("kaikatsu", server.URL), WithTransport(http.DefaultTransport))
+	if len(scraper.sources) != len(RegisteredSources()) || scraper.sources[0].ChainID() != "kaikatsu" {
+		t.Fatalf("expected the built-in source to be replaced in place, got %d sources", len(scraper.sources))
+	}
+	cafes, err := scraper.sources[0].Scrape(context.Background(), scraper)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cafes) != 1 || cafes[0].Name != "新宿西口店" {
+		t.Errorf("unexpected stores: %+v", cafes)
+	}
+}
+
+func TestParseSourceSpecs_Errors(t *testing.T) {
+	valid := `
+  - id: test
+    name: テスト
+    base_url: https://example.com
+    start_url: /
+    lists:
+      - item: li
+        fields:
+          name:
+            - selector: h3
+`
+	tests := map[string]string{
+		"unknown key":         "sources:" + valid + "    unknown: 1\n",
+		"duplicate id":        "sources:" + valid + valid,
+		"missing name":        strings.Replace("sources:"+valid, "name: テスト", "", 1),
+		"relative base url":   strings.Replace("sources:"+valid, "https://example.com", "example.com", 1),
+		"invalid id":          strings.Replace("sources:"+valid, "id: test", "id: Test Cafe", 1),
+		"no lists":            "sources:\n  - id: test\n    name: テスト\n    base_url: https://example.com\n    start_url: /\n",
+		"invalid selector":    strings.Replace("sources:"+valid, "selector: h3", "selector: 'h3[['", 1),
+		"invalid pattern":     strings.Replace("sources:"+valid, "selector: h3", "pattern: '(店'", 1),
+		"value with selector": strings.Replace("sources:"+valid, "selector: h3", "selector: h3\n              value: 店", 1),
+	}
+	for name, content := range tests {
+		if _, err := parseSourceSpecs([]byte(content), "test.yaml"); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	if _, err := parseSourceSpecs([]byte(`{"sources": [], "extra": true}`), "test.json"); err == nil {
+		t.Error("unknown JSON keys should be rejected")
+	}
+	if _, err := LoadSourceSpecs(t.TempDir()); err == nil {
+		t.Error("expected error for a directory without definitions")
+	}
+}
+
+func TestParseSourceSpecs_DeprecatedNamePrefix(t *testing.T) {
+	content := `
+sources:
+  - id: test
+    name: テスト
+    name_prefix: "テスト "
+    base_url: https://example.com
+    start_url: /
+    lists:
+      - item: li
+        fields:
+          name:
+            - selector: h3
+`
+	specs, err := parseSourceSpecs([]byte(content), "test.yaml")
+	if err != nil {
+		t.Fatalf("name_prefix should still be accepted: %v", err)
+	}
+	if len(specs) != 1 || specs[0].Name != "テスト" {
+		t.Errorf("unexpected specs: %+v", specs)
+	}
+}
+
+func TestExtractor(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`
+		<div class="item">
+			<span class="name">新宿店</span>
+			<a class="map" href="/map?q=新宿">地図</a>
+			<p>営業時間：10:00〜22:00（年中無休）</p>
+		</div>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := doc.Find(".item")
+
+	tests := []struct {
+		extractor Extractor
+		expected  string
+	}{
+		{Extractor{Selector: ".name"}, "新宿店"},
+		{Extractor{Selector: ".map", Attr: "href"}, "/map?q=新宿"},
+		{Extractor{Selector: ".missing"}, ""},
+		{Extractor{Pattern: `営業時間：(\S+?)（`}, "10:00〜22:00"},
+		{Extractor{Selector: "p", Pattern: `\d+:\d+`}, "10:00"},
+		{Extractor{Selector: "p", Pattern: `定休日`}, ""},
+		{Extractor{Value: "24時間営業"}, "24時間営業"},
+	}
+	for _, tt := range tests {
+		e := tt.extractor
+		if err := e.compile(); err != nil {
+			t.Fatalf("%+v: %v", e, err)
+		}
+		if got := e.extract(item); got != tt.expected {
+			t.Errorf("%+v: got %q, expected %q", tt.extractor, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,434 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// GeoPoint は店舗の緯度・経度。
+type GeoPoint struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+func (g GeoPoint) String() string {
+	return fmt.Sprintf("%g,%g", g.Lat, g.Lng)
+}
+
+// businessTypes は店舗として扱う schema.org の型。
+var businessTypes = map[string]bool{
+	"LocalBusiness":         true,
+	"InternetCafe":          true,
+	"EntertainmentBusiness": true,
+	"Store":                 true,
+}
+
+// structuredStores は doc に埋め込まれた schema.org の構造化データ（JSON-LD と microdata）から
+// 店舗を取り出す。チェーンは src のもので、名前の先頭にチェーン名があれば取り除く。
+// 構造化データがなければ nil を返すので、そのときは各取得元のセレクターで解析する。
+func structuredStores(doc *goquery.Document, src Source, base string) []NetCafe {
+	var cafes []NetCafe
+	for _, item := range structuredItems(doc) {
+		c := businessFromItem(item, base)
+		if c.Name == "" {
+			continue
+		}
+		c.setChain(sourceChain(src))
+		cafes = append(cafes, c)
+	}
+	return cafes
+}
+
+// structuredDetail は詳細ページの構造化データを StoreDetail にする。店舗が1件だけのときに限る。
+func structuredDetail(doc *goquery.Document, base string) (StoreDetail, bool) {
+	items := structuredItems(doc)
+	if len(items) != 1 {
+		return StoreDetail{}, false
+	}
+	c := businessFromItem(items[0], base)
+	return StoreDetail{
+		Location:  c.Location,
+		Hours:     c.Hours,
+		Phone:     c.Phone,
+		Geo:       c.Geo,
+		Amenities: c.Amenities,
+	}, true
+}
+
+// structuredItems は JSON-LD と microdata の店舗を、JSON-LD の形の map にそろえて返す。
+func structuredItems(doc *goquery.Document) []map[string]any {
+	var items []map[string]any
+	doc.Find(`script[type="application/ld+json"]`).Each(func(i int, s *goquery.Selection) {
+		var v any
+		if err := json.Unmarshal([]byte(s.Text()), &v); err != nil {
+			log.Printf("ignoring invalid JSON-LD: %v", err)
+			return
+		}
+		items = appendBusinesses(items, v)
+	})
+
+	doc.Find("[itemscope][itemtype]").Each(func(i int, s *goquery.Selection) {
+		if !isBusinessType(s.AttrOr("itemtype", "")) {
+			return
+		}
+		// 別の店舗の中に入れ子になったもの（系列店の一覧など）は外側の店舗の一部として扱わない
+		if s.ParentsFiltered("[itemscope]").FilterFunction(func(i int, p *goquery.Selection) bool {
+			return isBusinessType(p.AttrOr("itemtype", ""))
+		}).Length() > 0 {
+			return
+		}
+		items = append(items, microdataItem(s))
+	})
+	return items
+}
+
+// appendBusinesses は JSON-LD の値 v をたどり、店舗の型のオブジェクトを items に加える。
+// @graph や ItemList のように入れ子になった店舗も拾う。
+func appendBusinesses(items []map[string]any, v any) []map[string]any {
+	switch v := v.(type) {
+	case []any:
+		for _, e := range v {
+			items = appendBusinesses(items, e)
+		}
+	case map[string]any:
+		for _, t := range stringValues(v["@type"]) {
+			if isBusinessType(t) {
+				return append(items, v)
+			}
+		}
+		for _, e := range v {
+			items = appendBusinesses(items, e)
+		}
+	}
+	return items
+}
+
+// isBusinessType は "InternetCafe" や "https://schema.org/InternetCafe" が店舗の型かどうかを返す。
+func isBusinessType(t string) bool {
+	for _, typ := range strings.Fields(t) {
+		typ = typ[strings.LastIndexAny(typ, "/:")+1:]
+		if businessTypes[typ] {
+			return true
+		}
+	}
+	return false
+}
+
+// microdataItem は itemscope の要素を JSON-LD と同じ形の map にする。
+// 同じ itemprop が複数あれば配列に、入れ子の itemscope は map にする。
+func microdataItem(scope *goquery.Selection) map[string]any {
+	item := map[string]any{"@type": scope.AttrOr("itemtype", "")}
+	scope.Find("[itemprop]").Each(func(i int, s *goquery.Selection) {
+		if owner := s.ParentsFiltered("[itemscope]").First(); owner.Length() == 0 || owner.Get(0) != scope.Get(0) {
+			return
+		}
+		var value any
+		if _, ok := s.Attr("itemscope"); ok {
+			value = microdataItem(s)
+		} else {
+			value = microdataValue(s)
+		}
+		for _, name := range strings.Fields(s.AttrOr("itemprop", "")) {
+			switch existing := item[name].(type) {
+			case nil:
+				item[name] = value
+			case []any:
+				item[name] = append(existing, value)
+			default:
+				item[name] = []any{existing, value}
+			}
+		}
+	})
+	return item
+}
+
+func microdataValue(s *goquery.Selection) string {
+	for _, attr := range []string{"content", "href", "src", "datetime"} {
+		if v, ok := s.Attr(attr); ok {
+			return strings.TrimSpace(v)
+		}
+	}
+	return strings.TrimSpace(s.Text())
+}
+
+// businessFromItem は schema.org の LocalBusiness を NetCafe にする。
+func businessFromItem(item map[string]any, base string) NetCafe {
+	c := NetCafe{
+		Name:     firstString(item["name"]),
+		Location: postalAddress(item["address"]),
+		Phone:    strings.TrimPrefix(firstString(item["telephone"]), "tel:"),
+		URL:      absoluteURL(base, firstString(item["url"])),
+	}
+
+	var specs []hoursSpec
+	for _, spec := range objectValues(item["openingHoursSpecification"]) {
+		specs = append(specs, hoursSpec{
+			days:   stringValues(spec["dayOfWeek"]),
+			opens:  firstString(spec["opens"]),
+			closes: firstString(spec["closes"]),
+		})
+	}
+	for _, text := range stringValues(item["openingHours"]) {
+		specs = append(specs, parseOpeningHoursProperty(text)...)
+	}
+	c.Hours = formatHoursSpecs(specs)
+
+	if geo := objectValues(item["geo"]); len(geo) > 0 {
+		lat, latErr := strconv.ParseFloat(firstString(geo[0]["latitude"]), 64)
+		lng, lngErr := strconv.ParseFloat(firstString(geo[0]["longitude"]), 64)
+		if latErr == nil && lngErr == nil {
+			c.Geo = &GeoPoint{Lat: lat, Lng: lng}
+		}
+	}
+
+	for _, f := range objectValues(item["amenityFeature"]) {
+		if name := firstString(f["name"]); name != "" && firstString(f["value"]) != "false" {
+			c.Amenities = append(c.Amenities, name)
+		}
+	}
+	return c
+}
+
+// postalAddress は文字列または PostalAddress の住所を「都道府県 市区町村 番地」の順につなげる。
+func postalAddress(v any) string {
+	if s := firstString(v); s != "" {
+		return s
+	}
+	addrs := objectValues(v)
+	if len(addrs) == 0 {
+		return ""
+	}
+	a := addrs[0]
+	addr := firstString(a["streetAddress"])
+	for _, part := range []string{firstString(a["addressLocality"]), firstString(a["addressRegion"])} {
+		if !strings.HasPrefix(addr, part) {
+			addr = part + addr
+		}
+	}
+	return addr
+}
+
+// firstString は JSON の値が文字列・数値・真偽値ならその文字列を、配列なら最初の要素の文字列を返す。
+func firstString(v any) string {
+	switch v := v.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case []any:
+		if len(v) > 0 {
+			return firstString(v[0])
+		}
+	}
+	return ""
+}
+
+func stringValues(v any) []string {
+	if list, ok := v.([]any); ok {
+		var values []string
+		for _, e := range list {
+			if s := firstString(e); s != "" {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	if s := firstString(v); s != "" {
+		return []string{s}
+	}
+	return nil
+}
+
+func objectValues(v any) []map[string]any {
+	switch v := v.(type) {
+	case map[string]any:
+		return []map[string]any{v}
+	case []any:
+		var objects []map[string]any
+		for _, e := range v {
+			if m, ok := e.(map[string]any); ok {
+				objects = append(objects, m)
+			}
+		}
+		return objects
+	}
+	return nil
+}
+
+// hoursSpec は schema.org の営業時間の1行（曜日と開店・閉店時刻）。
+type hoursSpec struct {
+	days   []string
+	opens  string
+	closes string
+}
+
+var (
+	schemaDays = map[string]time.Weekday{
+		"Sunday": time.Sunday, "Monday": time.Monday, "Tuesday": time.Tuesday, "Wednesday": time.Wednesday,
+		"Thursday": time.Thursday, "Friday": time.Friday, "Saturday": time.Saturday,
+		"Su": time.Sunday, "Mo": time.Monday, "Tu": time.Tuesday, "We": time.Wednesday,
+		"Th": time.Thursday, "Fr": time.Friday, "Sa": time.Saturday,
+	}
+	// weekOrder は営業時間のテキストに曜日を並べる順（月曜始まり）。
+	weekOrder = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday}
+	dayNames  = [7]string{"日", "月", "火", "水", "木", "金", "土"}
+)
+
+// parseOpeningHoursProperty は openingHours プロパティの「Mo-Fr 10:00-22:00」「Sa,Su 09:00-23:00」
+// 「Mo-Su」のような値を解析する。時刻がなければ終日営業とみなす。
+func parseOpeningHoursProperty(text string) []hoursSpec {
+	dayPart, timePart, ok := strings.Cut(strings.TrimSpace(text), " ")
+	if !ok {
+		if strings.Contains(dayPart, ":") {
+			dayPart, timePart = "Mo-Su", dayPart
+		} else {
+			timePart = "00:00-24:00"
+		}
+	}
+	opens, closes, ok := strings.Cut(strings.TrimSpace(timePart), "-")
+	if !ok {
+		return nil
+	}
+
+	var days []string
+	for _, item := range strings.Split(dayPart, ",") {
+		first, last, isRange := strings.Cut(item, "-")
+		start, ok := schemaDays[first]
+		if !ok {
+			continue
+		}
+		end := start
+		if isRange {
+			if end, ok = schemaDays[last]; !ok {
+				continue
+			}
+		}
+		for d := start; ; d = (d + 1) % 7 {
+			days = append(days, d.String())
+			if d == end {
+				break
+			}
+		}
+	}
+	return []hoursSpec{{days: days, opens: opens, closes: closes}}
+}
+
+// formatHoursSpecs は営業時間を ParseOpeningHours が解析できる「月〜金 10:00〜22:00 / 土日祝 9:00〜23:00」
+// の形のテキストにする。毎日同じ時間なら曜日を省き、終日営業なら「24時間営業」とする。
+func formatHoursSpecs(specs []hoursSpec) string {
+	var weekly [7]string
+	holiday := ""
+	for _, spec := range specs {
+		r := formatSchemaRange(spec.opens, spec.closes)
+		if r == "" {
+			continue
+		}
+		for _, day := range spec.days {
+			day = day[strings.LastIndexAny(day, "/:")+1:]
+			if day == "PublicHolidays" {
+				holiday = r
+			} else if d, ok := schemaDays[day]; ok {
+				weekly[d] = r
+			}
+		}
+	}
+
+	// 時間帯ごとに曜日をまとめる（月曜始まりで最初に現れた順）
+	var ranges []string
+	days := map[string][]time.Weekday{}
+	for _, d := range weekOrder {
+		if r := weekly[d]; r != "" {
+			if _, ok := days[r]; !ok {
+				ranges = append(ranges, r)
+			}
+			days[r] = append(days[r], d)
+		}
+	}
+	if len(ranges) == 0 {
+		return ""
+	}
+	if len(ranges) == 1 && len(days[ranges[0]]) == 7 && (holiday == "" || holiday == ranges[0]) {
+		if ranges[0] == "0:00〜翌0:00" {
+			return "24時間営業"
+		}
+		return ranges[0]
+	}
+
+	var groups []string
+	for _, r := range ranges {
+		spec := formatDaySpec(days[r])
+		if r == holiday {
+			spec += "祝"
+			holiday = ""
+		}
+		groups = append(groups, spec+" "+r)
+	}
+	if holiday != "" {
+		groups = append(groups, "祝 "+holiday)
+	}
+	return strings.Join(groups, " / ")
+}
+
+// formatSchemaRange は "10:00"・"05:00:00" のような開店・閉店時刻を「10:00〜翌5:00」にする。
+func formatSchemaRange(opens, closes string) string {
+	start, ok := schemaClock(opens)
+	if !ok {
+		return ""
+	}
+	end, ok := schemaClock(closes)
+	if !ok {
+		return ""
+	}
+	if end == minutesPerDay-1 {
+		// 23:59 閉店は終日営業の慣用的な書き方
+		end = minutesPerDay
+	}
+	next := ""
+	if end <= start || end >= minutesPerDay {
+		next = "翌"
+		end = end % minutesPerDay
+	}
+	return fmt.Sprintf("%d:%02d〜%s%d:%02d", start/60, start%60, next, end/60, end%60)
+}
+
+func schemaClock(s string) (int, bool) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) < 2 {
+		return 0, false
+	}
+	h, err1 := strconv.Atoi(parts[0])
+	m, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil || h < 0 || h > 24 || m < 0 || m > 59 {
+		return 0, false
+	}
+	return h*60 + m, true
+}
+
+// formatDaySpec は曜日の一覧を「月〜金」「土日」のような表記にする。3日以上続く曜日は範囲にする。
+func formatDaySpec(days []time.Weekday) string {
+	index := func(d time.Weekday) int { return (int(d) + 6) % 7 } // 月曜を0とした位置
+	var b strings.Builder
+	for i := 0; i < len(days); {
+		j := i
+		for j+1 < len(days) && index(days[j+1]) == index(days[j])+1 {
+			j++
+		}
+		if j-i >= 2 {
+			b.WriteString(dayNames[days[i]] + "〜" + dayNames[days[j]])
+		} else {
+			for _, d := range days[i : j+1] {
+				b.WriteString(dayNames[d])
+			}
+		}
+		i = j + 1
+	}
+	return b.String()
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestScraper_ScrapeKaikatsu_JSONLD(t *testing.T) {
+	htmlContent := `
+	<html>
+	<head>
+	<script type="application/ld+json">
+	{
+		"@context": "https://schema.org",
+		"@type": "ItemList",
+		"itemListElement": [
+			{
+				"@type": "ListItem",
+				"position": 1,
+				"item": {
+					"@type": "InternetCafe",
+					"name": "新宿西口店",
+					"url": "/shop/shinjuku/",
+					"telephone": "03-5321-6166",
+					"address": {
+						"@type": "PostalAddress",
+						"addressRegion": "東京都",
+						"addressLocality": "新宿区",
+						"streetAddress": "西新宿1-12-9"
+					},
+					"openingHoursSpecification": {
+						"@type": "OpeningHoursSpecification",
+						"dayOfWeek": ["Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"],
+						"opens": "00:00",
+						"closes": "23:59"
+					},
+					"geo": {"@type": "GeoCoordinates", "latitude": 35.6909, "longitude": 139.6995}
+				}
+			},
+			{
+				"@type": "ListItem",
+				"position": 2,
+				"item": {
+					"@type": ["LocalBusiness", "InternetCafe"],
+					"name": "快活CLUB 池袋店",
+					"address": "東京都豊島区西池袋1-1-1",
+					"openingHours": ["Mo-Fr 10:00-22:00", "Sa,Su 09:00-05:00"]
+				}
+			}
+		]
+	}
+	</script>
+	<script type="application/ld+json">{"@type": "Organization", "name": "株式会社快活フロンティア"}</script>
+	</head>
+	<body>
+		<div class="shop-list-item"><h3 class="shop-name">セレクターの店</h3></div>
+	</body>
+	</html>
+	`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(htmlContent))
+	}))
+	defer server.Close()
+
+	scraper := NewScraper(WithBaseURL("kaikatsu", server.URL), WithTransport(http.DefaultTransport))
+	cafes, err := builtinSource(t, "kaikatsu").Scrape(context.Background(), scraper)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []NetCafe{
+		{
+			Name:     "新宿西口店",
+			Chain:    Chain{ID: "kaikatsu", Name: "快活CLUB"},
+			Location: "東京都新宿区西新宿1-12-9",
+			Hours:    "24時間営業",
+			Phone:    "03-5321-6166",
+			URL:      server.URL + "/shop/shinjuku/",
+			Geo:      &GeoPoint{Lat: 35.6909, Lng: 139.6995},
+		},
+		{
+			Name:     "池袋店",
+			Chain:    Chain{ID: "kaikatsu", Name: "快活CLUB"},
+			Location: "東京都豊島区西池袋1-1-1",
+			Hours:    "月〜金 10:00〜22:00 / 土日 9:00〜翌5:00",
+		},
+	}
+	if !reflect.DeepEqual(cafes, expected) {
+		t.Errorf("unexpected stores from JSON-LD:\n got %+v\nwant %+v", cafes, expected)
+	}
+}
+
+func TestScraper_ScrapeJiqoo_Microdata(t *testing.T) {
+	htmlContent := `
+	<html><body>
+		<div class="shop-item" itemscope itemtype="https://schema.org/InternetCafe">
+			<h3 class="shop-name" itemprop="name">池袋西口ROSA店</h3>
+			<div itemprop="address" itemscope itemtype="https://schema.org/PostalAddress">
+				<span itemprop="addressRegion">東京都</span><span itemprop="addressLocality">豊島区</span>
+				<span itemprop="streetAddress">西池袋1-37-12</span>
+			</div>
+			<a itemprop="telephone" href="tel:03-1234-5678">03-1234-5678</a>
+			<meta itemprop="openingHours" content="Mo-Su">
+			<div itemprop="geo" itemscope itemtype="https://schema.org/GeoCoordinates">
+				<meta itemprop="latitude" content="35.7295"><meta itemprop="longitude" content="139.7109">
+			</div>
+		</div>
+	</body></html>
+	`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(htmlContent))
+	}))
+	defer server.Close()
+
+	scraper := NewScraper(WithBaseURL("jiqoo", server.URL), WithTransport(http.DefaultTransport))
+	cafes, err := builtinSource(t, "jiqoo").Scrape(context.Background(), scraper)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cafes) != 1 {
+		t.Fatalf("expected 1 store, got %+v", cafes)
+	}
+	c := cafes[0]
+	if c.Name != "池袋西口ROSA店" || c.Location != "東京都豊島区西池袋1-37-12" || c.Hours != "24時間営業" {
+		t.Errorf("unexpected store from microdata: %+v", c)
+	}
+	if c.Phone != "03-1234-5678" {
+		t.Errorf("unexpected phone: %q", c.Phone)
+	}
+	if c.Geo == nil || *c.Geo != (GeoPoint{Lat: 35.7295, Lng: 139.7109}) {
+		t.Errorf("unexpected geo: %v", c.Geo)
+	}
+}
+
+func TestScraper_ScrapeAll_StructuredDetail(t *testing.T) {
+	listHTML := `<div class="shop-list-item"><h3 class="shop-name">新宿西口店</h3><a href="/shop/shinjuku/">詳細</a></div>`
+	detailHTML := `
+	<html><head>
+	<script type="application/ld+json">
+	{"@context": "https://schema.org", "@type": "InternetCafe", "name": "快活CLUB 新宿西口店",
+	 "address": "東京都新宿区西新宿1-12-9",
+	 "openingHoursSpecification": [
+		{"dayOfWeek": ["https://schema.org/Monday", "https://schema.org/Tuesday", "https://schema.org/Wednesday", "https://schema.org/Thursday", "https://schema.org/Friday"], "opens": "10:00", "closes": "05:00"},
+		{"dayOfWeek": ["Saturday", "Sunday", "PublicHolidays"], "opens": "09:00:00", "closes": "23:00:00"}
+	 ],
+	 "amenityFeature": [
+		{"@type": "LocationFeatureSpecification", "name": "シャワー", "value": true},
+		{"@type": "LocationFeatureSpecification", "name": "喫煙室", "value": false}
+	 ]}
+	</script>
+	</head><body>
+		<div class="shop-detail">
+			<div class="shop-hours">セレクターの営業時間</div>
+			<div class="shop-tel">03-5321-6166</div>
+		</div>
+		<ul class="price-list"><li>3時間パック 1,200円</li></ul>
+	</body></html>
+	`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/shop/tokyo/":
+			w.Write([]byte(listHTML))
+		case "/shop/shinjuku/":
+			w.Write([]byte(detailHTML))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	scraper := NewScraper(WithBaseURL("kaikatsu", server.URL), WithTransport(http.DefaultTransport), WithDetails(1))
+	scraper.sources = []Source{builtinSource(t, "kaikatsu")}
+	result, err := scraper.ScrapeAll(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c := result.Stores[0]
+	// 構造化データの項目が優先され、構造化データにない電話番号・料金はセレクターで補う
+	if c.Hours != "月〜金 10:00〜翌5:00 / 土日祝 9:00〜23:00" || c.Location != "東京都新宿区西新宿1-12-9" {
+		t.Errorf("structured data should take precedence: %+v", c)
+	}
+	if c.Phone != "03-5321-6166" || !reflect.DeepEqual(c.Prices, []Price{{Plan: "3時間パック", Yen: 1200}}) {
+		t.Errorf("selectors should fill missing fields: %+v", c)
+	}
+	if !reflect.DeepEqual(c.Amenities, []string{"シャワー"}) {
+		t.Errorf("unexpected amenities: %v", c.Amenities)
+	}
+}
+
+func TestStructuredStores_NotFound(t *testing.T) {
+	htmlContent := `
+	<html><head>
+	<script type="application/ld+json">{"@type": "WebSite", "name": "快活CLUB"}</script>
+	<script type="application/ld+json">{ invalid</script>
+	</head><body>
+		<div itemscope itemtype="https://schema.org/BreadcrumbList"><span itemprop="name">店舗一覧</span></div>
+	</body></html>`
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cafes := structuredStores(doc, builtinSource(t, "kaikatsu"), "https://www.kaikatsu.jp"); cafes != nil {
+		t.Errorf("expected no stores, got %+v", cafes)
+	}
+}
+
+func TestFormatHoursSpecs(t *testing.T) {
+	weekdays := []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday"}
+	tests := []struct {
+		specs    []hoursSpec
+		expected string
+	}{
+		{parseOpeningHoursProperty("Mo-Su 00:00-24:00"), "24時間営業"},
+		{parseOpeningHoursProperty("Mo-Su 10:00-22:00"), "10:00〜22:00"},
+		{[]hoursSpec{{days: weekdays, opens: "10:00", closes: "22:00"}}, "月〜金 10:00〜22:00"},
+		{
+			[]hoursSpec{{days: []string{"Monday", "Wednesday", "Friday", "Saturday", "Sunday"}, opens: "11:00", closes: "02:00"}},
+			"月水金〜日 11:00〜翌2:00",
+		},
+		{
+			append(parseOpeningHoursProperty("Mo-Sa 10:00-22:00"), hoursSpec{days: []string{"PublicHolidays"}, opens: "12:00", closes: "20:00"}),
+			"月〜土 10:00〜22:00 / 祝 12:00〜20:00",
+		},
+		{[]hoursSpec{{days: weekdays, opens: "closed", closes: ""}}, ""},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := formatHoursSpecs(tt.specs); got != tt.expected {
+			t.Errorf("formatHoursSpecs(%+v) = %q, expected %q", tt.specs, got, tt.expected)
+		}
+	}
+}
+
+func TestFormatHoursSpecs_RoundTrip(t *testing.T) {
+	// 組み立てた営業時間テキストは ParseOpeningHours で解析できる
+	specs := append(parseOpeningHoursProperty("Mo-Fr 10:00-05:00"),
+		hoursSpec{days: []string{"Saturday", "PublicHolidays"}, opens: "09:00", closes: "23:00"})
+	text := formatHoursSpecs(specs)
+	h, err := ParseOpeningHours(text)
+	if err != nil {
+		t.Fatalf("ParseOpeningHours(%q) returned error: %v", text, err)
+	}
+
+	at := func(day, hour int) time.Time { return time.Date(2026, 10, day, hour, 0, 0, 0, jst) }
+	tests := []struct {
+		t        time.Time
+		expected bool
+	}{
+		{at(20, 3), true},   // 火曜 3時（月曜の営業の続き）
+		{at(21, 8), false},  // 水曜 8時
+		{at(24, 22), true},  // 土曜 22時
+		{at(25, 12), false}, // 日曜は休業
+	}
+	for _, tt := range tests {
+		if got := h.IsOpenAt(tt.t); got != tt.expected {
+			t.Errorf("%q: IsOpenAt(%v) = %v, expected %v", text, tt.t, got, tt.expected)
+		}
+	}
+}
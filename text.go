@@ -0,0 +1,34 @@
+package main
+
+import "strings"
+
+// normalizeWidth は全角英数字・記号を半角に、全角スペースを半角スペースに変換する。
+func normalizeWidth(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= '！' && r <= '～':
+			return r - 0xFEE0
+		case r == '　':
+			return ' '
+		}
+		return r
+	}, s)
+}
+
+var dashReplacer = strings.NewReplacer(
+	"\u2010", "-",
+	"\u2011", "-",
+	"\u2012", "-",
+	"\u2013", "-",
+	"\u2014", "-",
+	"\u2015", "-",
+	"\u2212", "-",
+	"\u30fc", "-",
+	"\u301c", "~",
+)
+
+// normalizeDashes はハイフンや波ダッシュの異体字を ASCII の '-' と '~' にそろえる。
+// 長音記号「ー」もハイフンとして扱うので、住所・電話番号・時刻など数字まわりの文字列にだけ使う。
+func normalizeDashes(s string) string {
+	return dashReplacer.Replace(s)
+}
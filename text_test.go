@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestNormalizeWidth(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"０３－５３２１－６１６６", "03-5321-6166"},
+		{"１０：００～２２：００", "10:00~22:00"},
+		{"ＴＥＬ　０３", "TEL 03"},
+		{"新宿区", "新宿区"},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeWidth(tt.input); got != tt.expected {
+			t.Errorf("normalizeWidth(%q): expected %q, got %q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestNormalizeDashes(t *testing.T) {
+	if got := normalizeDashes("1ー12−9〜"); got != "1-12-9~" {
+		t.Errorf("unexpected result: %q", got)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// defaultRequestTimeout は1回の HTTP リクエスト（レスポンスの本文を読み終えるまで）の時間の上限。
+const defaultRequestTimeout = 15 * time.Second
+
+// ErrRequestTimeout は TimeoutTransport の時間の上限を過ぎたリクエストで返る。
+var ErrRequestTimeout = errors.New("request timed out")
+
+// TimeoutTransport はリクエスト1回ごとに時間の上限を設ける RoundTripper。
+// http.Client.Timeout と違い、上の層でのレート制限や再試行の待ち時間を含まない。
+// PoliteTransport と RetryTransport の下に置く。
+type TimeoutTransport struct {
+	next    http.RoundTripper
+	timeout time.Duration
+}
+
+// NewTimeoutTransport は1回のリクエストを timeout で打ち切る TimeoutTransport を返す。
+// next が nil なら http.DefaultTransport を使う。timeout が0以下なら打ち切らない。
+func NewTimeoutTransport(next http.RoundTripper, timeout time.Duration) *TimeoutTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &TimeoutTransport{next: next, timeout: timeout}
+}
+
+func (t *TimeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.timeout <= 0 {
+		return t.next.RoundTrip(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		if ctx.Err() == context.DeadlineExceeded && req.Context().Err() == nil {
+			return nil, fmt.Errorf("%w after %v: %s", ErrRequestTimeout, t.timeout, req.URL)
+		}
+		return nil, err
+	}
+	// 本文を読み終えるまでが1回のリクエスト。Close で context を解放する
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
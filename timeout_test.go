@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimeoutTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: NewTimeoutTransport(nil, 50*time.Millisecond)}
+
+	resp, err := client.Get(server.URL + "/fast")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if body, err := io.ReadAll(resp.Body); err != nil || string(body) != "ok" {
+		t.Errorf("unexpected body %q: %v", body, err)
+	}
+	resp.Body.Close()
+
+	if _, err := client.Get(server.URL + "/slow"); !errors.Is(err, ErrRequestTimeout) {
+		t.Errorf("expected ErrRequestTimeout, got %v", err)
+	}
+
+	// 呼び出し側の context の打ち切りは ErrRequestTimeout にしない
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/slow", nil)
+	if _, err := client.Do(req); err == nil || errors.Is(err, ErrRequestTimeout) {
+		t.Errorf("expected the caller's context error, got %v", err)
+	}
+}
+
+func TestTimeoutTransport_RateWaitNotCounted(t *testing.T) {
+	server, _ := robotsServer(t, http.StatusNotFound, "")
+	// レート制限の待ち時間（200ms）はリクエスト1回の上限（100ms）より長いが、上限には含めない
+	client := &http.Client{Transport: NewPoliteTransport(NewTimeoutTransport(nil, 100*time.Millisecond), "", 5)}
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		resp, err := client.Get(server.URL + "/shop/")
+		if err != nil {
+			t.Fatalf("request %d: unexpected error: %v", i, err)
+		}
+		resp.Body.Close()
+	}
+	if elapsed := time.Since(start); elapsed < 350*time.Millisecond {
+		t.Errorf("expected requests to be rate limited, took %v", elapsed)
+	}
+}